@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"micrypt/internal/bip39"
@@ -22,6 +24,8 @@ type App struct {
 	entropyCollector *crypto.EntropyCollector
 	pendingMnemonic  []string
 	storedMnemonic   []string
+	reencryptMu      sync.Mutex
+	reencryptCancel  context.CancelFunc
 }
 
 type FileInfo struct {
@@ -32,6 +36,53 @@ type FileInfo struct {
 	EncryptedAt   time.Time `json:"encryptedAt"`
 }
 
+type KeySlotInfo struct {
+	ID           string    `json:"id"`
+	Label        string    `json:"label"`
+	Primary      bool      `json:"primary"`
+	UsesKeyfiles bool      `json:"usesKeyfiles"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type IdentityInfo struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label"`
+	Recipient string    `json:"recipient"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type UnlockResult struct {
+	VaultPath       string   `json:"vaultPath"`
+	DropboxIngested int      `json:"dropboxIngested"`
+	DropboxFailed   int      `json:"dropboxFailed"`
+	Warnings        []string `json:"warnings"`
+}
+
+type RecoveryResult struct {
+	VaultPath        string   `json:"vaultPath"`
+	CredentialsReset bool     `json:"credentialsReset"`
+	DropboxIngested  int      `json:"dropboxIngested"`
+	DropboxFailed    int      `json:"dropboxFailed"`
+	Warnings         []string `json:"warnings"`
+}
+
+type WordSuggestion struct {
+	Position    int      `json:"position"`
+	Word        string   `json:"word"`
+	Suggestions []string `json:"suggestions"`
+}
+
+type RecoveryWordsCheck struct {
+	Language       string           `json:"language"`
+	Valid          bool             `json:"valid"`
+	WordCountValid bool             `json:"wordCountValid"`
+	ChecksumValid  bool             `json:"checksumValid"`
+	ChecksumWord   int              `json:"checksumWord"`
+	UnknownWords   []WordSuggestion `json:"unknownWords"`
+	Corrections    []WordSuggestion `json:"corrections"`
+	Message        string           `json:"message"`
+}
+
 type VaultStats struct {
 	TotalFiles int    `json:"totalFiles"`
 	TotalSize  int64  `json:"totalSize"`
@@ -39,7 +90,18 @@ type VaultStats struct {
 	IsUnlocked bool   `json:"isUnlocked"`
 }
 
+type SecurityStatus struct {
+	Platform          string   `json:"platform"`
+	DumpsDisabled     bool     `json:"dumpsDisabled"`
+	CoreDumpsDisabled bool     `json:"coreDumpsDisabled"`
+	MemoryLocked      bool     `json:"memoryLocked"`
+	VaultKeysLocked   bool     `json:"vaultKeysLocked"`
+	VaultKeysGuarded  bool     `json:"vaultKeysGuarded"`
+	Warnings          []string `json:"warnings"`
+}
+
 func NewApp() *App {
+	crypto.HardenProcess()
 	return &App{}
 }
 
@@ -47,6 +109,25 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 }
 
+func (a *App) GetSecurityStatus() SecurityStatus {
+	hardening := crypto.HardenProcess()
+	status := SecurityStatus{
+		Platform:          hardening.Platform,
+		DumpsDisabled:     hardening.DumpsDisabled,
+		CoreDumpsDisabled: hardening.CoreDumpsDisabled,
+		MemoryLocked:      hardening.MemoryLocked,
+		Warnings:          hardening.Warnings,
+	}
+	if a.currentVault != nil && a.currentVault.IsUnlocked() {
+		status.VaultKeysLocked = a.currentVault.KeysLocked()
+		status.VaultKeysGuarded = a.currentVault.KeysGuarded()
+		if !status.VaultKeysLocked && !status.MemoryLocked {
+			status.Warnings = append(status.Warnings, "vault keys could not be locked in memory and may be swapped to disk")
+		}
+	}
+	return status
+}
+
 func (a *App) StartEntropyCollection() {
 	a.entropyCollector = crypto.NewEntropyCollector()
 }
@@ -71,7 +152,7 @@ func (a *App) IsEntropyComplete() bool {
 	return a.entropyCollector.IsComplete()
 }
 
-func (a *App) CreateVault(password string, algorithm int, pim uint32, keyfiles []string, directory string) (string, error) {
+func (a *App) CreateVault(password string, algorithm int, pim uint32, keyfiles []string, directory string, securityLevel int, mnemonicWords int, mnemonicLanguage string, recoveryPassphrase string, storeRecoveryPhrase bool) (string, error) {
 	if len(password) < 8 {
 		return "", fmt.Errorf("password must be at least 8 characters")
 	}
@@ -106,19 +187,7 @@ func (a *App) CreateVault(password string, algorithm int, pim uint32, keyfiles [
 	}
 
 	vaultPath := location
-	var cascadeMode crypto.CascadeMode
-	switch algorithm {
-	case 0:
-		cascadeMode = crypto.SingleCipher
-	case 1:
-		cascadeMode = crypto.AESSerpent
-	case 2:
-		cascadeMode = crypto.AESTwofish
-	case 3:
-		cascadeMode = crypto.AESTwofishSerpent
-	default:
-		cascadeMode = crypto.AESTwofishSerpent
-	}
+	cascadeMode := cascadeModeForAlgorithm(algorithm)
 
 	var entropySeed []byte
 	if a.entropyCollector != nil && a.entropyCollector.IsComplete() {
@@ -129,14 +198,30 @@ func (a *App) CreateVault(password string, algorithm int, pim uint32, keyfiles [
 		a.entropyCollector = nil
 	}
 
+	kdfParams, err := kdfParamsForSecurityLevel(securityLevel)
+	if err != nil {
+		crypto.WipeBytes(entropySeed)
+		return "", err
+	}
+
 	keyfileBytes, err := decodeKeyfiles(keyfiles)
 	if err != nil {
+		crypto.WipeBytes(entropySeed)
 		return "", err
 	}
 	defer wipeKeyfiles(keyfileBytes)
 
-	options := &vault.VaultCreationOptions{Keyfiles: keyfileBytes, PIM: pim, Entropy: entropySeed}
-	v, mnemonic, err := vault.CreateVaultWithEntropyOptions(vaultPath, password, cascadeMode, entropySeed, options)
+	options := &vault.VaultCreationOptions{
+		Keyfiles:           keyfileBytes,
+		PIM:                pim,
+		Entropy:            entropySeed,
+		KDFParams:          kdfParams,
+		MnemonicWords:      mnemonicWords,
+		MnemonicLanguage:   mnemonicLanguage,
+		RecoveryPassphrase: []byte(recoveryPassphrase),
+		OmitStoredMnemonic: !storeRecoveryPhrase,
+	}
+	v, mnemonic, err := vault.CreateVaultWithEntropyOptions(vaultPath, []byte(password), cascadeMode, entropySeed, options)
 	if err != nil {
 		crypto.WipeBytes(entropySeed)
 		return "", err
@@ -146,14 +231,17 @@ func (a *App) CreateVault(password string, algorithm int, pim uint32, keyfiles [
 	actualPath := v.GetPath()
 	a.vaultPath = actualPath
 	a.pendingMnemonic = append([]string(nil), mnemonic.Words...)
-	a.storedMnemonic = append([]string(nil), mnemonic.Words...)
+	if v.HasStoredMnemonic() {
+		a.storedMnemonic = append([]string(nil), mnemonic.Words...)
+	}
 	crypto.WipeBytes(mnemonic.Seed)
 	crypto.WipeBytes(entropySeed)
 
 	return actualPath, nil
 }
 
-func (a *App) UnlockVault(password string, pim uint32, keyfiles []string, directory string) error {
+func (a *App) UnlockVault(password string, pim uint32, keyfiles []string, directory string) (UnlockResult, error) {
+	var result UnlockResult
 	location := directory
 	if location == "" {
 		var err error
@@ -165,37 +253,57 @@ func (a *App) UnlockVault(password string, pim uint32, keyfiles []string, direct
 			},
 		})
 		if err != nil {
-			return err
+			return result, err
 		}
 		if location == "" {
-			return fmt.Errorf("no vault file selected")
+			return result, fmt.Errorf("no vault file selected")
 		}
 	}
 
 	if info, err := os.Stat(location); err == nil && info.IsDir() {
-		return fmt.Errorf("expected a vault file but got a directory")
+		return result, fmt.Errorf("expected a vault file but got a directory")
 	}
 
 	if !vault.VaultExists(location) {
-		return fmt.Errorf("no vault found at this location")
+		return result, fmt.Errorf("no vault found at this location")
 	}
 
 	keyfileBytes, err := decodeKeyfiles(keyfiles)
 	if err != nil {
-		return err
+		return result, err
 	}
 	defer wipeKeyfiles(keyfileBytes)
 
 	unlockOpts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
-	v, err := vault.OpenVaultWithOptions(location, password, unlockOpts)
+	v, err := vault.OpenVaultWithOptions(location, []byte(password), unlockOpts)
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	a.currentVault = v
 	a.vaultPath = v.GetPath()
+	report := v.UnlockReport()
+	result.VaultPath = a.vaultPath
+	result.DropboxIngested = report.DropboxIngested
+	result.DropboxFailed = report.DropboxFailed
+	result.Warnings = unlockWarnings(report)
 
-	return nil
+	return result, nil
+}
+
+func unlockWarnings(report vault.UnlockReport) []string {
+	warnings := []string{}
+	if report.KDFUpgradeError != nil {
+		warnings = append(warnings, fmt.Sprintf("the key derivation upgrade could not be saved: %v", report.KDFUpgradeError))
+	}
+	if report.DropboxError != nil {
+		if report.DropboxFailed > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d drop box file(s) could not be imported and remain pending: %v", report.DropboxFailed, report.DropboxError))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("the drop box could not be read: %v", report.DropboxError))
+		}
+	}
+	return warnings
 }
 
 func (a *App) LockVault() error {
@@ -258,7 +366,9 @@ func (a *App) GetRecoveryMnemonic() []string {
 	if len(a.pendingMnemonic) > 0 {
 		words := append([]string(nil), a.pendingMnemonic...)
 		a.pendingMnemonic = nil
-		a.storedMnemonic = append([]string(nil), words...)
+		if a.currentVault.HasStoredMnemonic() {
+			a.storedMnemonic = append([]string(nil), words...)
+		}
 		return words
 	}
 	if len(a.storedMnemonic) > 0 {
@@ -277,27 +387,529 @@ func (a *App) RequestRecoveryMnemonic(password string, pim uint32) ([]string, er
 	if a.currentVault == nil {
 		return nil, fmt.Errorf("no vault is currently open")
 	}
-	if err := a.currentVault.VerifyPassword(password, &vault.UnlockOptions{PIM: pim}); err != nil {
+	if err := a.currentVault.VerifyPassword([]byte(password), &vault.UnlockOptions{PIM: pim}); err != nil {
 		return nil, err
 	}
 	words := a.currentVault.StoredMnemonic()
 	if len(words) == 0 {
-		return nil, fmt.Errorf("no recovery phrase available")
+		return nil, fmt.Errorf("the recovery phrase is not stored in this vault and cannot be shown again")
 	}
 	a.storedMnemonic = append([]string(nil), words...)
 	return append([]string(nil), words...), nil
 }
 
-func (a *App) RecoverVaultWithSeed(words []string, directory string) (string, error) {
-	if len(words) == 0 {
-		return "", fmt.Errorf("mnemonic words required")
+func (a *App) SaveRecoveryKit(words []string, recoveryPassphrase string) (string, error) {
+	if a.currentVault == nil {
+		return "", fmt.Errorf("no vault is currently open")
+	}
+
+	destPath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save Recovery Kit",
+		DefaultFilename: "micrypt-recovery-kit.html",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "HTML Document (*.html)", Pattern: "*.html"},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if destPath == "" {
+		return "", nil
+	}
+
+	if err := a.currentVault.WriteRecoveryKit(words, []byte(recoveryPassphrase), destPath); err != nil {
+		return "", err
+	}
+	return destPath, nil
+}
+
+func (a *App) GetMnemonicLanguages() []string {
+	return bip39.Languages()
+}
+
+func (a *App) CheckRecoveryWords(words []string) RecoveryWordsCheck {
+	report := bip39.CheckMnemonic(words, "")
+	result := RecoveryWordsCheck{
+		Language:       report.Language,
+		Valid:          report.Valid(),
+		WordCountValid: report.WordCountValid,
+		ChecksumValid:  report.ChecksumValid,
+		ChecksumWord:   report.ChecksumWord,
+		UnknownWords:   wordSuggestions(report.UnknownWords),
+		Corrections:    wordSuggestions(report.Corrections),
+	}
+	if err := report.Err(); err != nil {
+		result.Message = err.Error()
+	}
+	return result
+}
+
+func wordSuggestions(issues []bip39.WordIssue) []WordSuggestion {
+	out := make([]WordSuggestion, 0, len(issues))
+	for _, issue := range issues {
+		out = append(out, WordSuggestion{
+			Position:    issue.Position,
+			Word:        issue.Word,
+			Suggestions: issue.Suggestions,
+		})
+	}
+	return out
+}
+
+func (a *App) IsRecoveryPhraseStored() bool {
+	return a.currentVault != nil && a.currentVault.HasStoredMnemonic()
+}
+
+func (a *App) ForgetRecoveryPhrase(password string, pim uint32, keyfiles []string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
+	if err != nil {
+		return err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	if err := a.currentVault.ForgetRecoveryPhrase([]byte(password), &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}); err != nil {
+		return err
+	}
+	a.pendingMnemonic = nil
+	a.storedMnemonic = nil
+	return nil
+}
+
+func (a *App) RecoveryUsesPassphrase() bool {
+	return a.currentVault != nil && a.currentVault.RecoveryUsesPassphrase()
+}
+
+func (a *App) CreateRecoveryShares(password string, pim uint32, recoveryPassphrase string, threshold int, count int) ([][]string, error) {
+	if a.currentVault == nil {
+		return nil, fmt.Errorf("no vault is currently open")
+	}
+
+	return a.currentVault.CreateRecoveryShares([]byte(password), &vault.UnlockOptions{PIM: pim}, []byte(recoveryPassphrase), threshold, count)
+}
+
+func (a *App) ChangeVaultCredentials(oldPassword string, oldPIM uint32, oldKeyfiles []string, newPassword string, newPIM uint32, newKeyfiles []string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	oldKeyfileBytes, err := decodeKeyfiles(oldKeyfiles)
+	if err != nil {
+		return err
+	}
+	defer wipeKeyfiles(oldKeyfileBytes)
+
+	newKeyfileBytes, err := decodeKeyfiles(newKeyfiles)
+	if err != nil {
+		return err
+	}
+	defer wipeKeyfiles(newKeyfileBytes)
+
+	oldOpts := &vault.UnlockOptions{Keyfiles: oldKeyfileBytes, PIM: oldPIM}
+	newOpts := &vault.UnlockOptions{Keyfiles: newKeyfileBytes, PIM: newPIM}
+	return a.currentVault.ChangeCredentials([]byte(oldPassword), oldOpts, []byte(newPassword), newOpts)
+}
+
+func (a *App) RotateVaultMasterKey(password string, pim uint32, keyfiles []string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
+	if err != nil {
+		return err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	opts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
+	return a.currentVault.RotateMasterKey([]byte(password), opts)
+}
+
+func (a *App) RotateRecoveryPhrase(password string, pim uint32, keyfiles []string, recoveryPassphrase string) ([]string, error) {
+	if a.currentVault == nil {
+		return nil, fmt.Errorf("no vault is currently open")
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	opts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
+	mnemonic, err := a.currentVault.RotateRecoveryPhrase([]byte(password), opts, []byte(recoveryPassphrase))
+	if err != nil {
+		return nil, err
+	}
+	crypto.WipeBytes(mnemonic.Seed)
+	a.pendingMnemonic = nil
+	a.storedMnemonic = nil
+	return mnemonic.Words, nil
+}
+
+func (a *App) CreateRecoveryFile(password string, pim uint32, keyfiles []string) (string, error) {
+	if a.currentVault == nil {
+		return "", fmt.Errorf("no vault is currently open")
 	}
-	mnemonic, err := bip39.RestoreFromMnemonic(words, "")
+
+	destPath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save Recovery File",
+		DefaultFilename: strings.TrimSuffix(filepath.Base(a.currentVault.GetPath()), filepath.Ext(a.currentVault.GetPath())) + ".mrecovery",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Micrypt Recovery File (*.mrecovery)", Pattern: "*.mrecovery"},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if destPath == "" {
+		return "", nil
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
 	if err != nil {
 		return "", err
 	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	opts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
+	if err := a.currentVault.CreateRecoveryFile([]byte(password), opts, destPath); err != nil {
+		return "", err
+	}
+	return destPath, nil
+}
+
+func (a *App) RemoveRecoveryFile(password string, pim uint32, keyfiles []string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
+	if err != nil {
+		return err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	return a.currentVault.RemoveRecoveryFile([]byte(password), &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim})
+}
+
+func (a *App) HasRecoveryFile() bool {
+	return a.currentVault != nil && a.currentVault.HasRecoveryFile()
+}
+
+func (a *App) ListKeySlots() ([]KeySlotInfo, error) {
+	if a.currentVault == nil {
+		return nil, fmt.Errorf("no vault is currently open")
+	}
+
+	slots := a.currentVault.ListKeySlots()
+	result := make([]KeySlotInfo, len(slots))
+	for i, slot := range slots {
+		result[i] = keySlotInfoFromVault(slot)
+	}
+	return result, nil
+}
+
+func (a *App) AddKeySlot(password string, pim uint32, keyfiles []string, label string, slotPassword string, slotPIM uint32, slotKeyfiles []string) (KeySlotInfo, error) {
+	if a.currentVault == nil {
+		return KeySlotInfo{}, fmt.Errorf("no vault is currently open")
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
+	if err != nil {
+		return KeySlotInfo{}, err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	slotKeyfileBytes, err := decodeKeyfiles(slotKeyfiles)
+	if err != nil {
+		return KeySlotInfo{}, err
+	}
+	defer wipeKeyfiles(slotKeyfileBytes)
+
+	opts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
+	slotOpts := &vault.UnlockOptions{Keyfiles: slotKeyfileBytes, PIM: slotPIM}
+	slot, err := a.currentVault.AddKeySlot([]byte(password), opts, label, []byte(slotPassword), slotOpts)
+	if err != nil {
+		return KeySlotInfo{}, err
+	}
+	return keySlotInfoFromVault(*slot), nil
+}
+
+func (a *App) RemoveKeySlot(password string, pim uint32, keyfiles []string, id string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
+	if err != nil {
+		return err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	opts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
+	return a.currentVault.RemoveKeySlot([]byte(password), opts, id)
+}
+
+func keySlotInfoFromVault(slot vault.KeySlotInfo) KeySlotInfo {
+	return KeySlotInfo{
+		ID:           slot.ID,
+		Label:        slot.Label,
+		Primary:      slot.Primary,
+		UsesKeyfiles: slot.UsesKeyfiles,
+		CreatedAt:    slot.CreatedAt,
+	}
+}
+
+func (a *App) ListIdentities() ([]IdentityInfo, error) {
+	if a.currentVault == nil {
+		return nil, fmt.Errorf("no vault is currently open")
+	}
+
+	identities := a.currentVault.ListIdentities()
+	result := make([]IdentityInfo, len(identities))
+	for i, identity := range identities {
+		result[i] = identityInfoFromVault(identity)
+	}
+	return result, nil
+}
+
+func (a *App) GenerateIdentity(label string) (IdentityInfo, error) {
+	if a.currentVault == nil {
+		return IdentityInfo{}, fmt.Errorf("no vault is currently open")
+	}
+
+	identity, err := a.currentVault.GenerateIdentity(label)
+	if err != nil {
+		return IdentityInfo{}, err
+	}
+	return identityInfoFromVault(*identity), nil
+}
+
+func (a *App) ImportIdentity(label string, secret string) (IdentityInfo, error) {
+	if a.currentVault == nil {
+		return IdentityInfo{}, fmt.Errorf("no vault is currently open")
+	}
+
+	identity, err := a.currentVault.ImportIdentity(label, secret)
+	if err != nil {
+		return IdentityInfo{}, err
+	}
+	return identityInfoFromVault(*identity), nil
+}
+
+func (a *App) ExportIdentity(id string) (string, error) {
+	if a.currentVault == nil {
+		return "", fmt.Errorf("no vault is currently open")
+	}
+
+	return a.currentVault.ExportIdentity(id)
+}
+
+func (a *App) RemoveIdentity(id string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	return a.currentVault.RemoveIdentity(id)
+}
+
+func (a *App) ShareFileWithRecipients(encryptedName string, recipients []string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	destPath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save Shared File",
+		DefaultFilename: a.currentVault.GetOriginalFilename(encryptedName) + ".mcrb",
+	})
+	if err != nil {
+		return err
+	}
+	if destPath == "" {
+		return nil
+	}
+
+	return a.currentVault.ExportEntryToRecipients(encryptedName, recipients, destPath)
+}
+
+func (a *App) ImportSharedFile() error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Shared File",
+	})
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+
+	_, err = a.currentVault.ImportRecipientBundle(path)
+	return err
+}
+
+func (a *App) ExportFileAsAge(encryptedName string, recipients []string, passphrase string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	destPath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save age File",
+		DefaultFilename: a.currentVault.GetOriginalFilename(encryptedName) + ".age",
+	})
+	if err != nil {
+		return err
+	}
+	if destPath == "" {
+		return nil
+	}
+
+	return a.currentVault.ExportEntryAsAge(encryptedName, destPath, recipients, []byte(passphrase))
+}
+
+func (a *App) ImportAgeFile(identity string, passphrase string) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select age File",
+	})
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+
+	_, err = a.currentVault.ImportAge(path, identity, []byte(passphrase))
+	return err
+}
+
+func (a *App) EnableDropbox() (string, error) {
+	if a.currentVault == nil {
+		return "", fmt.Errorf("no vault is currently open")
+	}
+
+	return a.currentVault.EnableDropbox()
+}
+
+func (a *App) DisableDropbox() error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	return a.currentVault.DisableDropbox()
+}
+
+func (a *App) GetDropboxRecipient() (string, error) {
+	if a.currentVault == nil {
+		return "", fmt.Errorf("no vault is currently open")
+	}
+
+	return a.currentVault.DropboxRecipient(), nil
+}
+
+func (a *App) AddFilesToDropbox(vaultPath string) error {
+	files, err := runtime.OpenMultipleFilesDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Files to Deposit",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range files {
+		if err := vault.AddToDropbox(vaultPath, filePath); err != nil {
+			return fmt.Errorf("failed to deposit %s: %v", filepath.Base(filePath), err)
+		}
+	}
+
+	return nil
+}
+
+func identityInfoFromVault(identity vault.IdentityInfo) IdentityInfo {
+	return IdentityInfo{
+		ID:        identity.ID,
+		Label:     identity.Label,
+		Recipient: identity.Recipient,
+		CreatedAt: identity.CreatedAt,
+	}
+}
+
+func (a *App) RecoverVaultWithSeed(words []string, recoveryPassphrase string, directory string, newPassword string, pim uint32, keyfiles []string) (RecoveryResult, error) {
+	var result RecoveryResult
+	if len(words) == 0 {
+		return result, fmt.Errorf("mnemonic words required")
+	}
+	passphrase := []byte(recoveryPassphrase)
+	mnemonic, err := bip39.RestoreFromMnemonic(words, passphrase)
+	crypto.WipeBytes(passphrase)
+	if err != nil {
+		return result, err
+	}
 	defer crypto.WipeBytes(mnemonic.Seed)
 
+	result, err = a.recoverVaultFromSeed(mnemonic.Seed, directory, newPassword, pim, keyfiles)
+	if err != nil {
+		return result, err
+	}
+	a.pendingMnemonic = append([]string(nil), mnemonic.Words...)
+	if a.currentVault.HasStoredMnemonic() {
+		a.storedMnemonic = append([]string(nil), mnemonic.Words...)
+	}
+	return result, nil
+}
+
+func (a *App) RecoverVaultWithShares(shares [][]string, recoveryPassphrase string, directory string, newPassword string, pim uint32, keyfiles []string) (RecoveryResult, error) {
+	var result RecoveryResult
+	if len(shares) == 0 {
+		return result, fmt.Errorf("recovery shares required")
+	}
+	words, err := bip39.CombineShares(shares)
+	if err != nil {
+		return result, err
+	}
+
+	return a.RecoverVaultWithSeed(words, recoveryPassphrase, directory, newPassword, pim, keyfiles)
+}
+
+func (a *App) recoverVaultFromSeed(seed []byte, directory string, newPassword string, pim uint32, keyfiles []string) (RecoveryResult, error) {
+	return a.recoverVault(directory, newPassword, pim, keyfiles, func(location string, opts *vault.UnlockOptions) (*vault.Vault, error) {
+		return vault.RecoverVaultWithSeed(location, seed, []byte(newPassword), opts)
+	})
+}
+
+func (a *App) RecoverVaultWithRecoveryFile(directory string, newPassword string, pim uint32, keyfiles []string) (RecoveryResult, error) {
+	var result RecoveryResult
+	file, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Recovery File",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Micrypt Recovery File (*.mrecovery)", Pattern: "*.mrecovery"},
+			{DisplayName: "All Files", Pattern: "*"},
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+	if file == "" {
+		return result, fmt.Errorf("no recovery file selected")
+	}
+
+	return a.recoverVault(directory, newPassword, pim, keyfiles, func(location string, opts *vault.UnlockOptions) (*vault.Vault, error) {
+		return vault.RecoverVaultWithRecoveryFile(location, file, []byte(newPassword), opts)
+	})
+}
+
+func (a *App) recoverVault(directory string, newPassword string, pim uint32, keyfiles []string, reset func(string, *vault.UnlockOptions) (*vault.Vault, error)) (RecoveryResult, error) {
+	var result RecoveryResult
+	var err error
 	location := directory
 	if location == "" {
 		location, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
@@ -308,31 +920,141 @@ func (a *App) RecoverVaultWithSeed(words []string, directory string) (string, er
 			},
 		})
 		if err != nil {
-			return "", err
+			return result, err
 		}
 		if location == "" {
-			return "", fmt.Errorf("no vault file selected")
+			return result, fmt.Errorf("no vault file selected")
 		}
 	}
 
 	if info, err := os.Stat(location); err == nil && info.IsDir() {
-		return "", fmt.Errorf("expected a vault file but got a directory")
+		return result, fmt.Errorf("expected a vault file but got a directory")
 	}
 
 	if !vault.VaultExists(location) {
-		return "", fmt.Errorf("no vault found at this location")
+		return result, fmt.Errorf("no vault found at this location")
 	}
 
-	v, err := vault.OpenVaultFromMnemonicSeed(location, mnemonic.Seed)
+	keyfileBytes, err := decodeKeyfiles(keyfiles)
 	if err != nil {
-		return "", err
+		return result, err
+	}
+	defer wipeKeyfiles(keyfileBytes)
+
+	resetOpts := &vault.UnlockOptions{Keyfiles: keyfileBytes, PIM: pim}
+	v, err := reset(location, resetOpts)
+	if err != nil {
+		return result, err
 	}
 
 	a.currentVault = v
 	a.vaultPath = v.GetPath()
-	a.pendingMnemonic = append([]string(nil), words...)
-	a.storedMnemonic = append([]string(nil), words...)
-	return location, nil
+	report := v.UnlockReport()
+	result.VaultPath = location
+	result.CredentialsReset = true
+	result.DropboxIngested = report.DropboxIngested
+	result.DropboxFailed = report.DropboxFailed
+	result.Warnings = unlockWarnings(report)
+	return result, nil
+}
+
+func (a *App) ReencryptVault(algorithm int) error {
+	if a.currentVault == nil {
+		return fmt.Errorf("no vault is currently open")
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.reencryptMu.Lock()
+	if a.reencryptCancel != nil {
+		a.reencryptMu.Unlock()
+		cancel()
+		return fmt.Errorf("a re-encryption is already running")
+	}
+	a.reencryptCancel = cancel
+	a.reencryptMu.Unlock()
+	defer func() {
+		a.reencryptMu.Lock()
+		a.reencryptCancel = nil
+		a.reencryptMu.Unlock()
+		cancel()
+	}()
+
+	progress := func(done, total int) {
+		runtime.EventsEmit(a.ctx, "vault:reencrypt-progress", done, total)
+	}
+	return a.currentVault.ReencryptAll(ctx, cascadeModeForAlgorithm(algorithm), progress)
+}
+
+func (a *App) CancelReencryption() {
+	a.reencryptMu.Lock()
+	defer a.reencryptMu.Unlock()
+	if a.reencryptCancel != nil {
+		a.reencryptCancel()
+	}
+}
+
+func (a *App) GetPendingReencryption() (int, error) {
+	if a.currentVault == nil {
+		return -1, fmt.Errorf("no vault is currently open")
+	}
+	mode, pending := a.currentVault.PendingReencryption()
+	if !pending {
+		return -1, nil
+	}
+	return algorithmForCascadeMode(mode), nil
+}
+
+func kdfParamsForSecurityLevel(level int) (*crypto.KDFParams, error) {
+	switch level {
+	case 0:
+		return nil, nil
+	case 1:
+		return crypto.CalibrateKDF(time.Second, 512*1024)
+	case 2:
+		return crypto.CalibrateKDF(3*time.Second, 1024*1024)
+	default:
+		return nil, fmt.Errorf("unknown security level")
+	}
+}
+
+func cascadeModeForAlgorithm(algorithm int) crypto.CascadeMode {
+	switch algorithm {
+	case 0:
+		return crypto.SingleCipher
+	case 1:
+		return crypto.AESSerpent
+	case 2:
+		return crypto.AESTwofish
+	case 3:
+		return crypto.AESTwofishSerpent
+	case 4:
+		return crypto.XChaCha20
+	case 5:
+		return crypto.XChaCha20AES
+	case 6:
+		return crypto.XChaCha20Serpent
+	default:
+		return crypto.AESTwofishSerpent
+	}
+}
+
+func algorithmForCascadeMode(mode crypto.CascadeMode) int {
+	switch mode {
+	case crypto.SingleCipher:
+		return 0
+	case crypto.AESSerpent:
+		return 1
+	case crypto.AESTwofish:
+		return 2
+	case crypto.XChaCha20:
+		return 4
+	case crypto.XChaCha20AES:
+		return 5
+	case crypto.XChaCha20Serpent:
+		return 6
+	default:
+		return 3
+	}
 }
 
 func decodeKeyfiles(encoded []string) ([][]byte, error) {
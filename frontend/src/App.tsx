@@ -15,6 +15,11 @@ import {
   GetHomeDirectory,
   GetVaultStats,
   RequestRecoveryMnemonic,
+  IsRecoveryPhraseStored,
+  SaveRecoveryKit,
+  RecoveryUsesPassphrase,
+  GetMnemonicLanguages,
+  CheckRecoveryWords,
 } from '../wailsjs/go/main/App';
 import { useTheme } from './hooks/useTheme';
 import { LockIcon, CatIcon, ShieldIcon, KeyIcon } from './components/Icons';
@@ -70,13 +75,28 @@ const createWordSlots = (count: number, seed: string[] = []) => {
   return slots;
 };
 
-const recoverWordOptions = [12];
+const recoverWordOptions = [12, 24];
+
+const formatLanguage = (language: string) =>
+  language
+    .split('_')
+    .map((part) => part.charAt(0).toUpperCase() + part.slice(1))
+    .join(' ');
 
 const algorithms = [
   { id: 0, name: 'AES-256-GCM', description: 'Fast and secure', icon: ShieldIcon },
   { id: 1, name: 'AES + Serpent', description: 'Double encryption', icon: ShieldIcon },
   { id: 2, name: 'AES + Twofish', description: 'Double encryption', icon: ShieldIcon },
   { id: 3, name: 'AES + Twofish + Serpent', description: 'Triple cascade (Maximum security)', icon: KeyIcon },
+  { id: 4, name: 'XChaCha20-Poly1305', description: 'Fast without AES hardware support', icon: ShieldIcon },
+  { id: 5, name: 'XChaCha20 + AES', description: 'Double encryption', icon: ShieldIcon },
+  { id: 6, name: 'XChaCha20 + Serpent', description: 'Double encryption without AES', icon: ShieldIcon },
+];
+
+const securityLevels = [
+  { id: 0, name: 'Standard', description: 'Default Argon2id cost' },
+  { id: 1, name: 'High', description: 'Tuned to ~1s on this device' },
+  { id: 2, name: 'Paranoid', description: 'Tuned to ~3s on this device' },
 ];
 
 function App() {
@@ -88,7 +108,18 @@ function App() {
   const [createConfirm, setCreateConfirm] = useState('');
   const [unlockPassword, setUnlockPassword] = useState('');
   const [selectedAlgorithm, setSelectedAlgorithm] = useState(3);
+  const [securityLevel, setSecurityLevel] = useState(0);
+  const [createWordCount, setCreateWordCount] = useState(12);
+  const [createMnemonicLanguage, setCreateMnemonicLanguage] = useState('english');
+  const [mnemonicLanguages, setMnemonicLanguages] = useState<string[]>(['english']);
+  const [createRecoveryPassphrase, setCreateRecoveryPassphrase] = useState('');
+  const [createStorePhrase, setCreateStorePhrase] = useState(true);
+  const [seedStored, setSeedStored] = useState(true);
+  const [seedKitMessage, setSeedKitMessage] = useState('');
+  const [seedKitPassphrase, setSeedKitPassphrase] = useState('');
+  const [seedUsesPassphrase, setSeedUsesPassphrase] = useState(false);
   const [error, setError] = useState('');
+  const [unlockWarnings, setUnlockWarnings] = useState<string[]>([]);
   const [loading, setLoading] = useState(false);
   const [seedWords, setSeedWords] = useState<string[] | null>(null);
   const [createPIM, setCreatePIM] = useState('');
@@ -100,6 +131,10 @@ function App() {
   const [createVaultPath, setCreateVaultPath] = useState('');
   const [unlockPath, setUnlockPath] = useState('');
   const [recoverPath, setRecoverPath] = useState('');
+  const [recoverPassword, setRecoverPassword] = useState('');
+  const [recoverConfirm, setRecoverConfirm] = useState('');
+  const [recoverPIM, setRecoverPIM] = useState('');
+  const [recoverPassphrase, setRecoverPassphrase] = useState('');
   const [createPathError, setCreatePathError] = useState('');
   const [unlockPathError, setUnlockPathError] = useState('');
   const [recoverPathError, setRecoverPathError] = useState('');
@@ -118,6 +153,25 @@ function App() {
   const mutedButtonClass = 'btn-muted';
   const inputClass = 'input-surface';
 
+  useEffect(() => {
+    GetMnemonicLanguages()
+      .then((languages) => {
+        if (languages && languages.length > 0) {
+          setMnemonicLanguages(languages);
+        }
+      })
+      .catch((err) => console.error('Failed to load word lists:', err));
+  }, []);
+
+  useEffect(() => {
+    if (currentScreen !== 'seed') {
+      return;
+    }
+    RecoveryUsesPassphrase()
+      .then(setSeedUsesPassphrase)
+      .catch(() => setSeedUsesPassphrase(false));
+  }, [currentScreen, seedWords]);
+
   useEffect(() => {
     const init = async () => {
       try {
@@ -158,6 +212,10 @@ function App() {
     setRecoverWords(createWordSlots(12));
     setRecoverPath('');
     setRecoverPathError('');
+    setRecoverPassword('');
+    setRecoverConfirm('');
+    setRecoverPIM('');
+    setRecoverPassphrase('');
   };
 
   const resetCreateForm = () => {
@@ -167,6 +225,11 @@ function App() {
     setCreateKeyfiles([]);
     setCreateVaultPath('');
     setCreatePathError('');
+    setSecurityLevel(0);
+    setCreateWordCount(12);
+    setCreateMnemonicLanguage('english');
+    setCreateRecoveryPassphrase('');
+    setCreateStorePhrase(true);
   };
 
   const handleChooseCreatePath = async () => {
@@ -264,7 +327,8 @@ function App() {
           return;
         }
       }
-      await UnlockVault(unlockPassword, pimValue, keyfileData, unlockPath || '');
+      const result = await UnlockVault(unlockPassword, pimValue, keyfileData, unlockPath || '');
+      setUnlockWarnings(result.warnings || []);
       setIsVaultUnlocked(true);
       setCurrentScreen('main');
       setCurrentView('files');
@@ -314,13 +378,25 @@ function App() {
     try {
       const pimValue = parsePimInput(createPIM);
       const keyfileData = createKeyfiles.map((item) => item.data);
-      const vaultPath = await CreateVault(createPassword, selectedAlgorithm, pimValue, keyfileData, createVaultPath);
+      const vaultPath = await CreateVault(
+        createPassword,
+        selectedAlgorithm,
+        pimValue,
+        keyfileData,
+        createVaultPath,
+        securityLevel,
+        createWordCount,
+        createMnemonicLanguage,
+        createRecoveryPassphrase,
+        createStorePhrase,
+      );
       setIsVaultUnlocked(true);
       setCurrentVaultPath(vaultPath);
       resetCreateForm();
       try {
         const words = await GetRecoveryMnemonic();
         if (words && words.length > 0) {
+          setSeedStored(await IsRecoveryPhraseStored());
           setSeedWords(words);
           setCurrentScreen('seed');
         } else {
@@ -345,12 +421,12 @@ function App() {
 
   const applyMnemonicWords = (values: string[]) => {
     const sanitized = values.map((word) => word.trim().toLowerCase()).filter((word) => word.length > 0);
-    if (sanitized.length !== 12) {
-      setError('Recovery phrase must contain exactly 12 words');
+    if (!recoverWordOptions.includes(sanitized.length)) {
+      setError('Recovery phrase must contain exactly 12 or 24 words');
       return false;
     }
-    setRecoverWordMode(12);
-    setRecoverWords(createWordSlots(12, sanitized));
+    setRecoverWordMode(sanitized.length);
+    setRecoverWords(createWordSlots(sanitized.length, sanitized));
     setError('');
     return true;
   };
@@ -365,7 +441,7 @@ function App() {
   };
 
   const handleRecoverWordChange = (index: number, value: string) => {
-    const sanitized = value.toLowerCase().replace(/[^a-z]/g, '');
+    const sanitized = value.toLowerCase().replace(/[^\p{L}\p{M}]/gu, '');
     setRecoverWords((prev) => {
       const next = [...prev];
       next[index] = sanitized;
@@ -414,6 +490,7 @@ function App() {
       if (!words || words.length === 0) {
         setSeedPromptError('No recovery phrase available');
       } else {
+        setSeedStored(true);
         setSeedWords(words);
         setSeedPromptOpen(false);
         setSeedPromptPassword('');
@@ -443,6 +520,14 @@ function App() {
       setError('Fill every recovery word');
       return;
     }
+    if (recoverPassword.length < 8) {
+      setError('New password must be at least 8 characters');
+      return;
+    }
+    if (recoverPassword !== recoverConfirm) {
+      setError('New passwords do not match');
+      return;
+    }
 
     setLoading(true);
     setError('');
@@ -456,11 +541,17 @@ function App() {
           return;
         }
       }
-      const dir = await RecoverVaultWithSeed(words, recoverPath || '');
+      const check = await CheckRecoveryWords(words);
+      if (!check.valid) {
+        setError(check.message);
+        return;
+      }
+      const result = await RecoverVaultWithSeed(words, recoverPassphrase, recoverPath || '', recoverPassword, parsePimInput(recoverPIM), []);
+      setUnlockWarnings(result.warnings || []);
       setIsVaultUnlocked(true);
       setCurrentScreen('main');
       setCurrentView('files');
-      setCurrentVaultPath(dir);
+      setCurrentVaultPath(result.vaultPath);
       resetRecoverForm();
     } catch (err: any) {
       const message = err?.toString?.() || 'Failed to recover vault';
@@ -486,6 +577,7 @@ function App() {
       setCurrentView('files');
       setCurrentVaultPath('');
       setSeedWords(null);
+      setUnlockWarnings([]);
       resetUnlockForm();
       resetRecoverForm();
     } catch (err: any) {
@@ -555,6 +647,7 @@ function App() {
       setCurrentView('files');
       setCurrentVaultPath('');
       setSeedWords(null);
+      setUnlockWarnings([]);
       resetUnlockForm();
       resetRecoverForm();
       resetCreateForm();
@@ -938,6 +1031,83 @@ function App() {
               })}
             </div>
 
+            <div className="space-y-2">
+              <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wide">Security level</label>
+              <div className="grid gap-2 md:grid-cols-3">
+                {securityLevels.map((level) => {
+                  const selected = securityLevel === level.id;
+                  return (
+                    <button
+                      key={level.id}
+                      type="button"
+                      onClick={() => setSecurityLevel(level.id)}
+                      className={`rounded-2xl border px-4 py-3 text-left transition ${selected ? 'border-gray-500 bg-gray-100 dark:bg-gray-800/70 dark:border-gray-500 text-gray-900 dark:text-gray-100' : 'border-gray-200/70 dark:border-gray-800 bg-white/70 dark:bg-gray-900/60 text-gray-600 dark:text-gray-300 hover:border-gray-500/70 hover:text-gray-900 dark:hover:text-white'}`}
+                    >
+                      <div className="text-sm font-semibold text-gray-900 dark:text-white">{level.name}</div>
+                      <div className="text-xs text-gray-500 dark:text-gray-400">{level.description}</div>
+                    </button>
+                  );
+                })}
+              </div>
+            </div>
+
+            <div className="space-y-2">
+              <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wide">Recovery phrase</label>
+              <div className="grid gap-2 md:grid-cols-2">
+                {recoverWordOptions.map((option) => {
+                  const selected = createWordCount === option;
+                  return (
+                    <button
+                      key={option}
+                      type="button"
+                      onClick={() => setCreateWordCount(option)}
+                      className={`rounded-2xl border px-4 py-3 text-left transition ${selected ? 'border-gray-500 bg-gray-100 dark:bg-gray-800/70 dark:border-gray-500 text-gray-900 dark:text-gray-100' : 'border-gray-200/70 dark:border-gray-800 bg-white/70 dark:bg-gray-900/60 text-gray-600 dark:text-gray-300 hover:border-gray-500/70 hover:text-gray-900 dark:hover:text-white'}`}
+                    >
+                      <div className="text-sm font-semibold text-gray-900 dark:text-white">{option} words</div>
+                      <div className="text-xs text-gray-500 dark:text-gray-400">{option === 12 ? '128-bit recovery seed' : '256-bit recovery seed'}</div>
+                    </button>
+                  );
+                })}
+              </div>
+              <select
+                value={createMnemonicLanguage}
+                onChange={(e) => setCreateMnemonicLanguage(e.target.value)}
+                className={inputClass}
+              >
+                {mnemonicLanguages.map((language) => (
+                  <option key={language} value={language}>
+                    {formatLanguage(language)} word list
+                  </option>
+                ))}
+              </select>
+              <input
+                type="password"
+                value={createRecoveryPassphrase}
+                onChange={(e) => setCreateRecoveryPassphrase(e.target.value)}
+                placeholder="Recovery passphrase (optional)"
+                autoComplete="off"
+                className={inputClass}
+              />
+              {createRecoveryPassphrase && (
+                <p className="text-xs text-gray-500 dark:text-gray-400">
+                  Recovery needs both the words and this passphrase. It is not stored anywhere.
+                </p>
+              )}
+              <label className="flex items-center gap-2 text-sm text-gray-700 dark:text-gray-300">
+                <input
+                  type="checkbox"
+                  checked={createStorePhrase}
+                  onChange={(e) => setCreateStorePhrase(e.target.checked)}
+                />
+                Keep an encrypted copy of the phrase in the vault
+              </label>
+              {!createStorePhrase && (
+                <p className="text-xs text-gray-500 dark:text-gray-400">
+                  The phrase is shown once after creation and can never be displayed again.
+                </p>
+              )}
+            </div>
+
             <div className="space-y-2">
               <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wide">Vault file</label>
               <div className="space-y-2">
@@ -1082,7 +1252,7 @@ function App() {
                 Recover Vault
               </h2>
               <p className="text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark font-medium">
-                Enter your 12-word recovery phrase to unlock the vault offline.
+                Enter your 12 or 24-word recovery phrase to unlock the vault offline.
               </p>
             </div>
             <div className="space-y-2">
@@ -1179,6 +1349,63 @@ function App() {
                   );
                 })}
               </div>
+              <input
+                type="password"
+                value={recoverPassphrase}
+                onChange={(e) => {
+                  setRecoverPassphrase(e.target.value);
+                  setError('');
+                }}
+                placeholder="Recovery passphrase (only if you set one)"
+                autoComplete="off"
+                disabled={loading}
+                className={inputClass}
+              />
+            </div>
+            <div className="space-y-3">
+              <div>
+                <span className="text-xs font-semibold uppercase tracking-wide text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark">
+                  New credentials
+                </span>
+                <p className="text-sm font-medium text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark">
+                  Your old password stops working once the vault is recovered
+                </p>
+              </div>
+              <input
+                type="password"
+                value={recoverPassword}
+                onChange={(e) => {
+                  setRecoverPassword(e.target.value);
+                  setError('');
+                }}
+                placeholder="New password"
+                disabled={loading}
+                className={inputClass}
+              />
+              <input
+                type="password"
+                value={recoverConfirm}
+                onChange={(e) => {
+                  setRecoverConfirm(e.target.value);
+                  setError('');
+                }}
+                onKeyPress={(e) => e.key === 'Enter' && handleRecoverWithSeed()}
+                placeholder="Confirm new password"
+                disabled={loading}
+                className={inputClass}
+              />
+              <input
+                type="number"
+                min="0"
+                value={recoverPIM}
+                onChange={(e) => {
+                  setRecoverPIM(e.target.value);
+                  setError('');
+                }}
+                placeholder="PIM (optional)"
+                disabled={loading}
+                className={inputClass}
+              />
             </div>
             {error && (
               <div className="px-5 py-4 rounded-neuro neuro-inset border-l-4 border-red-500">
@@ -1219,6 +1446,11 @@ function App() {
             <div className="text-center space-y-4">
               <h2 className="text-4xl font-bold text-neuro-text-primary-light dark:text-neuro-text-primary-dark tracking-tight">Recovery Seed Phrase</h2>
               <p className="text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark font-medium">Store these words securely; they are required to recover your vault.</p>
+              {!seedStored && (
+                <p className="text-sm font-bold text-red-600 dark:text-red-400">
+                  This vault does not keep a copy of the phrase. It will not be shown again.
+                </p>
+              )}
             </div>
             <div className="neuro-inset rounded-neuro p-6">
               <div className="grid grid-cols-3 gap-4 text-neuro-text-primary-light dark:text-neuro-text-primary-dark font-mono text-base">
@@ -1230,10 +1462,40 @@ function App() {
                 ))}
               </div>
             </div>
-            <div className="flex justify-center">
+            {seedUsesPassphrase && (
+              <input
+                type="password"
+                value={seedKitPassphrase}
+                onChange={(e) => setSeedKitPassphrase(e.target.value)}
+                placeholder="Recovery passphrase, to check the words before printing"
+                autoComplete="off"
+                className={inputClass}
+              />
+            )}
+            {seedKitMessage && (
+              <p className="text-center text-sm font-medium text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark">{seedKitMessage}</p>
+            )}
+            <div className="flex justify-center gap-3">
+              <button
+                onClick={async () => {
+                  try {
+                    const path = await SaveRecoveryKit(seedWords, seedKitPassphrase);
+                    if (path) {
+                      setSeedKitMessage(`Recovery kit saved to ${path}`);
+                    }
+                  } catch (err: any) {
+                    setSeedKitMessage(err?.toString?.() || 'Failed to save recovery kit');
+                  }
+                }}
+                className="neuro-card hover:neuro-card px-8 py-3 text-base font-bold rounded-neuro text-neuro-text-primary-light dark:text-neuro-text-primary-dark"
+              >
+                Save Printable Kit
+              </button>
               <button
                 onClick={() => {
                   setSeedWords(null);
+                  setSeedKitMessage('');
+                  setSeedKitPassphrase('');
                   setCurrentScreen('main');
                 }}
                 className="neuro-card hover:neuro-card px-8 py-3 text-base font-bold rounded-neuro text-neuro-text-primary-light dark:text-neuro-text-primary-dark"
@@ -1254,8 +1516,25 @@ function App() {
         <div className="flex h-screen bg-neuro-bg-light dark:bg-neuro-bg-dark">
           <DragRegion />
           <Sidebar currentView={currentView} onViewChange={setCurrentView} isVaultUnlocked={isVaultUnlocked} />
-          <div className="flex-1 overflow-hidden">
-            {renderView()}
+          <div className="flex-1 overflow-hidden flex flex-col">
+            {unlockWarnings.length > 0 && (
+              <div className="mx-6 mt-6 p-4 rounded-neuro bg-neuro-bg-light dark:bg-neuro-bg-dark shadow-neuro-light-inset dark:shadow-neuro-dark-inset flex items-start justify-between gap-4">
+                <ul className="space-y-1 text-sm text-red-600 dark:text-red-400">
+                  {unlockWarnings.map((warning) => (
+                    <li key={warning}>{warning}</li>
+                  ))}
+                </ul>
+                <button
+                  onClick={() => setUnlockWarnings([])}
+                  className="text-xs font-semibold text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark"
+                >
+                  Dismiss
+                </button>
+              </div>
+            )}
+            <div className="flex-1 overflow-hidden">
+              {renderView()}
+            </div>
           </div>
         </div>
       </>
@@ -1,4 +1,7 @@
+import { useEffect, useState } from 'react';
 import { LockIcon, ShieldIcon, KeyIcon, InfoIcon } from './Icons';
+import { GetSecurityStatus } from '../../wailsjs/go/main/App';
+import { main } from '../../wailsjs/go/models';
 
 interface SettingsViewProps {
   isVaultUnlocked: boolean;
@@ -22,6 +25,23 @@ export default function SettingsView({
   deletePending,
 }: SettingsViewProps) {
   const hasVaultPath = Boolean(vaultPath);
+  const [securityStatus, setSecurityStatus] = useState<main.SecurityStatus | null>(null);
+
+  useEffect(() => {
+    GetSecurityStatus()
+      .then(setSecurityStatus)
+      .catch((err) => console.error('Failed to load security status:', err));
+  }, [isVaultUnlocked]);
+
+  const protections = securityStatus
+    ? [
+        { label: 'Process dumps blocked', enabled: securityStatus.dumpsDisabled },
+        { label: 'Core dumps disabled', enabled: securityStatus.coreDumpsDisabled },
+        { label: 'Process memory locked', enabled: securityStatus.memoryLocked },
+        { label: 'Vault keys locked in memory', enabled: securityStatus.vaultKeysLocked },
+        { label: 'Vault keys behind guard pages', enabled: securityStatus.vaultKeysGuarded },
+      ]
+    : [];
   // TODO: Gebruik onDeleteVault opnieuw zodra de kluisverwijderingsinterface terugkeert.
   void onDeleteVault;
   void deletePending;
@@ -146,6 +166,35 @@ export default function SettingsView({
                 {lockPending ? 'Locking…' : 'Lock now'}
               </button>
             </div>
+            {securityStatus && (
+              <div className="p-5 rounded-neuro bg-neuro-bg-light dark:bg-neuro-bg-dark shadow-neuro-light-sm dark:shadow-neuro-dark-sm">
+                <div className="text-base font-bold text-neuro-text-primary-light dark:text-neuro-text-primary-dark mb-1">
+                  Memory protection
+                </div>
+                <div className="text-sm text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark font-semibold mb-3">
+                  Hardening applied to this process on {securityStatus.platform}
+                </div>
+                <ul className="space-y-1 text-sm font-semibold">
+                  {protections
+                    .filter((item) => isVaultUnlocked || !item.label.startsWith('Vault'))
+                    .map((item) => (
+                      <li key={item.label} className="flex items-center justify-between gap-4">
+                        <span className="text-neuro-text-primary-light dark:text-neuro-text-primary-dark">{item.label}</span>
+                        <span className={item.enabled ? 'text-green-600 dark:text-green-400' : 'text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark'}>
+                          {item.enabled ? 'Active' : 'Unavailable'}
+                        </span>
+                      </li>
+                    ))}
+                </ul>
+                {securityStatus.warnings && securityStatus.warnings.length > 0 && (
+                  <ul className="mt-3 space-y-1 text-xs text-neuro-text-secondary-light dark:text-neuro-text-secondary-dark">
+                    {securityStatus.warnings.map((warning) => (
+                      <li key={warning}>{warning}</li>
+                    ))}
+                  </ul>
+                )}
+              </div>
+            )}
           </div>
         </section>
 
@@ -6,7 +6,21 @@ export function AddEntropyEvent(arg1:number,arg2:number,arg3:number):Promise<voi
 
 export function AddFiles():Promise<void>;
 
-export function CreateVault(arg1:string,arg2:number,arg3:number,arg4:Array<string>,arg5:string):Promise<string>;
+export function AddFilesToDropbox(arg1:string):Promise<void>;
+
+export function AddKeySlot(arg1:string,arg2:number,arg3:Array<string>,arg4:string,arg5:string,arg6:number,arg7:Array<string>):Promise<main.KeySlotInfo>;
+
+export function CancelReencryption():Promise<void>;
+
+export function ChangeVaultCredentials(arg1:string,arg2:number,arg3:Array<string>,arg4:string,arg5:number,arg6:Array<string>):Promise<void>;
+
+export function CheckRecoveryWords(arg1:Array<string>):Promise<main.RecoveryWordsCheck>;
+
+export function CreateRecoveryFile(arg1:string,arg2:number,arg3:Array<string>):Promise<string>;
+
+export function CreateRecoveryShares(arg1:string,arg2:number,arg3:string,arg4:number,arg5:number):Promise<Array<Array<string>>>;
+
+export function CreateVault(arg1:string,arg2:number,arg3:number,arg4:Array<string>,arg5:string,arg6:number,arg7:number,arg8:string,arg9:string,arg10:boolean):Promise<string>;
 
 export function DeleteFile(arg1:string):Promise<void>;
 
@@ -14,36 +28,92 @@ export function DeleteVault():Promise<void>;
 
 export function DeleteVaultAtPath(arg1:string):Promise<void>;
 
+export function DisableDropbox():Promise<void>;
+
+export function EnableDropbox():Promise<string>;
+
+export function ExportFileAsAge(arg1:string,arg2:Array<string>,arg3:string):Promise<void>;
+
+export function ExportIdentity(arg1:string):Promise<string>;
+
 export function ExtractFile(arg1:string):Promise<void>;
 
+export function ForgetRecoveryPhrase(arg1:string,arg2:number,arg3:Array<string>):Promise<void>;
+
+export function GenerateIdentity(arg1:string):Promise<main.IdentityInfo>;
+
 export function GetCategoryStats():Promise<Record<string, number>>;
 
+export function GetDropboxRecipient():Promise<string>;
+
 export function GetEntropyProgress():Promise<number>;
 
 export function GetHomeDirectory():Promise<string>;
 
+export function GetMnemonicLanguages():Promise<Array<string>>;
+
+export function GetPendingReencryption():Promise<number>;
+
 export function GetRecoveryMnemonic():Promise<Array<string>>;
 
+export function GetSecurityStatus():Promise<main.SecurityStatus>;
+
 export function GetVaultStats():Promise<main.VaultStats>;
 
+export function HasRecoveryFile():Promise<boolean>;
+
+export function ImportAgeFile(arg1:string,arg2:string):Promise<void>;
+
+export function ImportIdentity(arg1:string,arg2:string):Promise<main.IdentityInfo>;
+
+export function ImportSharedFile():Promise<void>;
+
 export function IsEntropyComplete():Promise<boolean>;
 
+export function IsRecoveryPhraseStored():Promise<boolean>;
+
 export function IsVaultUnlocked():Promise<boolean>;
 
 export function ListFiles():Promise<Array<main.FileInfo>>;
 
+export function ListIdentities():Promise<Array<main.IdentityInfo>>;
+
+export function ListKeySlots():Promise<Array<main.KeySlotInfo>>;
+
 export function LockVault():Promise<void>;
 
-export function RecoverVaultWithSeed(arg1:Array<string>,arg2:string):Promise<string>;
+export function RecoverVaultWithRecoveryFile(arg1:string,arg2:string,arg3:number,arg4:Array<string>):Promise<main.RecoveryResult>;
+
+export function RecoverVaultWithSeed(arg1:Array<string>,arg2:string,arg3:string,arg4:string,arg5:number,arg6:Array<string>):Promise<main.RecoveryResult>;
+
+export function RecoverVaultWithShares(arg1:Array<Array<string>>,arg2:string,arg3:string,arg4:string,arg5:number,arg6:Array<string>):Promise<main.RecoveryResult>;
+
+export function RecoveryUsesPassphrase():Promise<boolean>;
+
+export function ReencryptVault(arg1:number):Promise<void>;
+
+export function RemoveIdentity(arg1:string):Promise<void>;
+
+export function RemoveKeySlot(arg1:string,arg2:number,arg3:Array<string>,arg4:string):Promise<void>;
+
+export function RemoveRecoveryFile(arg1:string,arg2:number,arg3:Array<string>):Promise<void>;
 
 export function RequestRecoveryMnemonic(arg1:string,arg2:number):Promise<Array<string>>;
 
+export function RotateRecoveryPhrase(arg1:string,arg2:number,arg3:Array<string>,arg4:string):Promise<Array<string>>;
+
+export function RotateVaultMasterKey(arg1:string,arg2:number,arg3:Array<string>):Promise<void>;
+
+export function SaveRecoveryKit(arg1:Array<string>,arg2:string):Promise<string>;
+
 export function SelectVaultDirectory():Promise<string>;
 
 export function SelectVaultFile():Promise<string>;
 
+export function ShareFileWithRecipients(arg1:string,arg2:Array<string>):Promise<void>;
+
 export function StartEntropyCollection():Promise<void>;
 
-export function UnlockVault(arg1:string,arg2:number,arg3:Array<string>,arg4:string):Promise<void>;
+export function UnlockVault(arg1:string,arg2:number,arg3:Array<string>,arg4:string):Promise<main.UnlockResult>;
 
 export function VaultExistsAtPath(arg1:string):Promise<boolean>;
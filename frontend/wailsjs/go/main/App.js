@@ -10,8 +10,36 @@ export function AddFiles() {
   return window['go']['main']['App']['AddFiles']();
 }
 
-export function CreateVault(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['CreateVault'](arg1, arg2, arg3, arg4, arg5);
+export function AddFilesToDropbox(arg1) {
+  return window['go']['main']['App']['AddFilesToDropbox'](arg1);
+}
+
+export function AddKeySlot(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['AddKeySlot'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function CancelReencryption() {
+  return window['go']['main']['App']['CancelReencryption']();
+}
+
+export function ChangeVaultCredentials(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['ChangeVaultCredentials'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function CheckRecoveryWords(arg1) {
+  return window['go']['main']['App']['CheckRecoveryWords'](arg1);
+}
+
+export function CreateRecoveryFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateRecoveryFile'](arg1, arg2, arg3);
+}
+
+export function CreateRecoveryShares(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CreateRecoveryShares'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function CreateVault(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10) {
+  return window['go']['main']['App']['CreateVault'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10);
 }
 
 export function DeleteFile(arg1) {
@@ -26,14 +54,42 @@ export function DeleteVaultAtPath(arg1) {
   return window['go']['main']['App']['DeleteVaultAtPath'](arg1);
 }
 
+export function DisableDropbox() {
+  return window['go']['main']['App']['DisableDropbox']();
+}
+
+export function EnableDropbox() {
+  return window['go']['main']['App']['EnableDropbox']();
+}
+
+export function ExportFileAsAge(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExportFileAsAge'](arg1, arg2, arg3);
+}
+
+export function ExportIdentity(arg1) {
+  return window['go']['main']['App']['ExportIdentity'](arg1);
+}
+
 export function ExtractFile(arg1) {
   return window['go']['main']['App']['ExtractFile'](arg1);
 }
 
+export function ForgetRecoveryPhrase(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ForgetRecoveryPhrase'](arg1, arg2, arg3);
+}
+
+export function GenerateIdentity(arg1) {
+  return window['go']['main']['App']['GenerateIdentity'](arg1);
+}
+
 export function GetCategoryStats() {
   return window['go']['main']['App']['GetCategoryStats']();
 }
 
+export function GetDropboxRecipient() {
+  return window['go']['main']['App']['GetDropboxRecipient']();
+}
+
 export function GetEntropyProgress() {
   return window['go']['main']['App']['GetEntropyProgress']();
 }
@@ -42,18 +98,50 @@ export function GetHomeDirectory() {
   return window['go']['main']['App']['GetHomeDirectory']();
 }
 
+export function GetMnemonicLanguages() {
+  return window['go']['main']['App']['GetMnemonicLanguages']();
+}
+
+export function GetPendingReencryption() {
+  return window['go']['main']['App']['GetPendingReencryption']();
+}
+
 export function GetRecoveryMnemonic() {
   return window['go']['main']['App']['GetRecoveryMnemonic']();
 }
 
+export function GetSecurityStatus() {
+  return window['go']['main']['App']['GetSecurityStatus']();
+}
+
 export function GetVaultStats() {
   return window['go']['main']['App']['GetVaultStats']();
 }
 
+export function HasRecoveryFile() {
+  return window['go']['main']['App']['HasRecoveryFile']();
+}
+
+export function ImportAgeFile(arg1, arg2) {
+  return window['go']['main']['App']['ImportAgeFile'](arg1, arg2);
+}
+
+export function ImportIdentity(arg1, arg2) {
+  return window['go']['main']['App']['ImportIdentity'](arg1, arg2);
+}
+
+export function ImportSharedFile() {
+  return window['go']['main']['App']['ImportSharedFile']();
+}
+
 export function IsEntropyComplete() {
   return window['go']['main']['App']['IsEntropyComplete']();
 }
 
+export function IsRecoveryPhraseStored() {
+  return window['go']['main']['App']['IsRecoveryPhraseStored']();
+}
+
 export function IsVaultUnlocked() {
   return window['go']['main']['App']['IsVaultUnlocked']();
 }
@@ -62,18 +150,66 @@ export function ListFiles() {
   return window['go']['main']['App']['ListFiles']();
 }
 
+export function ListIdentities() {
+  return window['go']['main']['App']['ListIdentities']();
+}
+
+export function ListKeySlots() {
+  return window['go']['main']['App']['ListKeySlots']();
+}
+
 export function LockVault() {
   return window['go']['main']['App']['LockVault']();
 }
 
-export function RecoverVaultWithSeed(arg1, arg2) {
-  return window['go']['main']['App']['RecoverVaultWithSeed'](arg1, arg2);
+export function RecoverVaultWithRecoveryFile(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RecoverVaultWithRecoveryFile'](arg1, arg2, arg3, arg4);
+}
+
+export function RecoverVaultWithSeed(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['RecoverVaultWithSeed'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function RecoverVaultWithShares(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['RecoverVaultWithShares'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function RecoveryUsesPassphrase() {
+  return window['go']['main']['App']['RecoveryUsesPassphrase']();
+}
+
+export function ReencryptVault(arg1) {
+  return window['go']['main']['App']['ReencryptVault'](arg1);
+}
+
+export function RemoveIdentity(arg1) {
+  return window['go']['main']['App']['RemoveIdentity'](arg1);
+}
+
+export function RemoveKeySlot(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RemoveKeySlot'](arg1, arg2, arg3, arg4);
+}
+
+export function RemoveRecoveryFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RemoveRecoveryFile'](arg1, arg2, arg3);
 }
 
 export function RequestRecoveryMnemonic(arg1, arg2) {
   return window['go']['main']['App']['RequestRecoveryMnemonic'](arg1, arg2);
 }
 
+export function RotateRecoveryPhrase(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RotateRecoveryPhrase'](arg1, arg2, arg3, arg4);
+}
+
+export function RotateVaultMasterKey(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RotateVaultMasterKey'](arg1, arg2, arg3);
+}
+
+export function SaveRecoveryKit(arg1, arg2) {
+  return window['go']['main']['App']['SaveRecoveryKit'](arg1, arg2);
+}
+
 export function SelectVaultDirectory() {
   return window['go']['main']['App']['SelectVaultDirectory']();
 }
@@ -82,6 +218,10 @@ export function SelectVaultFile() {
   return window['go']['main']['App']['SelectVaultFile']();
 }
 
+export function ShareFileWithRecipients(arg1, arg2) {
+  return window['go']['main']['App']['ShareFileWithRecipients'](arg1, arg2);
+}
+
 export function StartEntropyCollection() {
   return window['go']['main']['App']['StartEntropyCollection']();
 }
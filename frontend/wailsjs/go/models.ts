@@ -39,6 +39,188 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class IdentityInfo {
+	    id: string;
+	    label: string;
+	    recipient: string;
+	    // Go type: time
+	    createdAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new IdentityInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.label = source["label"];
+	        this.recipient = source["recipient"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class KeySlotInfo {
+	    id: string;
+	    label: string;
+	    primary: boolean;
+	    usesKeyfiles: boolean;
+	    // Go type: time
+	    createdAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new KeySlotInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.label = source["label"];
+	        this.primary = source["primary"];
+	        this.usesKeyfiles = source["usesKeyfiles"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RecoveryResult {
+	    vaultPath: string;
+	    credentialsReset: boolean;
+	    dropboxIngested: number;
+	    dropboxFailed: number;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RecoveryResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.vaultPath = source["vaultPath"];
+	        this.credentialsReset = source["credentialsReset"];
+	        this.dropboxIngested = source["dropboxIngested"];
+	        this.dropboxFailed = source["dropboxFailed"];
+	        this.warnings = source["warnings"];
+	    }
+	}
+	export class RecoveryWordsCheck {
+	    language: string;
+	    valid: boolean;
+	    wordCountValid: boolean;
+	    checksumValid: boolean;
+	    checksumWord: number;
+	    unknownWords: WordSuggestion[];
+	    corrections: WordSuggestion[];
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecoveryWordsCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.language = source["language"];
+	        this.valid = source["valid"];
+	        this.wordCountValid = source["wordCountValid"];
+	        this.checksumValid = source["checksumValid"];
+	        this.checksumWord = source["checksumWord"];
+	        this.unknownWords = this.convertValues(source["unknownWords"], WordSuggestion);
+	        this.corrections = this.convertValues(source["corrections"], WordSuggestion);
+	        this.message = source["message"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SecurityStatus {
+	    platform: string;
+	    dumpsDisabled: boolean;
+	    coreDumpsDisabled: boolean;
+	    memoryLocked: boolean;
+	    vaultKeysLocked: boolean;
+	    vaultKeysGuarded: boolean;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SecurityStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.platform = source["platform"];
+	        this.dumpsDisabled = source["dumpsDisabled"];
+	        this.coreDumpsDisabled = source["coreDumpsDisabled"];
+	        this.memoryLocked = source["memoryLocked"];
+	        this.vaultKeysLocked = source["vaultKeysLocked"];
+	        this.vaultKeysGuarded = source["vaultKeysGuarded"];
+	        this.warnings = source["warnings"];
+	    }
+	}
+	export class UnlockResult {
+	    vaultPath: string;
+	    dropboxIngested: number;
+	    dropboxFailed: number;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UnlockResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.vaultPath = source["vaultPath"];
+	        this.dropboxIngested = source["dropboxIngested"];
+	        this.dropboxFailed = source["dropboxFailed"];
+	        this.warnings = source["warnings"];
+	    }
+	}
 	export class VaultStats {
 	    totalFiles: number;
 	    totalSize: number;
@@ -57,6 +239,22 @@ export namespace main {
 	        this.isUnlocked = source["isUnlocked"];
 	    }
 	}
+	export class WordSuggestion {
+	    position: number;
+	    word: string;
+	    suggestions: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new WordSuggestion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.position = source["position"];
+	        this.word = source["word"];
+	        this.suggestions = source["suggestions"];
+	    }
+	}
 
 }
 
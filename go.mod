@@ -8,6 +8,7 @@ require (
 	github.com/wailsapp/wails/v2 v2.10.2
 	golang.org/x/crypto v0.43.0
 	golang.org/x/sys v0.37.0
+	golang.org/x/text v0.30.0
 )
 
 require (
@@ -35,5 +36,4 @@ require (
 	github.com/wailsapp/go-webview2 v1.0.22 // indirect
 	github.com/wailsapp/mimetype v1.4.1 // indirect
 	golang.org/x/net v0.46.0 // indirect
-	golang.org/x/text v0.30.0 // indirect
 )
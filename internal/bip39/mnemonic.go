@@ -3,11 +3,12 @@ package bip39
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
 	"errors"
 	"strings"
 
-	"github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
@@ -16,50 +17,59 @@ const (
 )
 
 type Mnemonic struct {
-	Words []string
-	Seed  []byte
+	Words    []string
+	Seed     []byte
+	Language string
 }
 
 func GenerateMnemonic(bits int) (*Mnemonic, error) {
+	return GenerateMnemonicWithPassphrase(bits, nil)
+}
+
+func GenerateMnemonicWithPassphrase(bits int, passphrase []byte) (*Mnemonic, error) {
+	return GenerateMnemonicInLanguage(bits, passphrase, LanguageEnglish)
+}
+
+func GenerateMnemonicInLanguage(bits int, passphrase []byte, language string) (*Mnemonic, error) {
 	if bits != Mnemonic12Words && bits != Mnemonic24Words {
 		return nil, errors.New("bits must be 128 or 256")
 	}
+	if len(language) == 0 {
+		language = LanguageEnglish
+	}
 
 	entropy := make([]byte, bits/8)
+	defer wipe(entropy)
 	if _, err := rand.Read(entropy); err != nil {
 		return nil, err
 	}
 
-	mnemonic, err := bip39.NewMnemonic(entropy)
-	if err != nil {
-		return nil, err
-	}
-
-	seed := bip39.NewSeed(mnemonic, "")
-
-	words, err := parseMnemonic(mnemonic)
+	words, err := entropyToMnemonic(entropy, language)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Mnemonic{
-		Words: words,
-		Seed:  seed,
+		Words:    words,
+		Seed:     mnemonicSeed(words, passphrase),
+		Language: language,
 	}, nil
 }
 
-func RestoreFromMnemonic(words []string, passphrase string) (*Mnemonic, error) {
-	mnemonic := joinWords(words)
+func RestoreFromMnemonic(words []string, passphrase []byte) (*Mnemonic, error) {
+	return RestoreFromMnemonicInLanguage(words, passphrase, "")
+}
 
-	if !bip39.IsMnemonicValid(mnemonic) {
-		return nil, errors.New("invalid mnemonic phrase")
+func RestoreFromMnemonicInLanguage(words []string, passphrase []byte, language string) (*Mnemonic, error) {
+	report := CheckMnemonic(words, language)
+	if err := report.Err(); err != nil {
+		return nil, err
 	}
 
-	seed := bip39.NewSeed(mnemonic, passphrase)
-
 	return &Mnemonic{
-		Words: words,
-		Seed:  seed,
+		Words:    report.Words,
+		Seed:     mnemonicSeed(report.Words, passphrase),
+		Language: report.Language,
 	}, nil
 }
 
@@ -73,26 +83,24 @@ func (m *Mnemonic) GetMasterKey() []byte {
 	return h.Sum(nil)
 }
 
-func parseMnemonic(mnemonic string) ([]string, error) {
-	words := strings.Fields(mnemonic)
-	if len(words) != 12 && len(words) != 24 {
-		return nil, errors.New("mnemonic must be 12 or 24 words")
+func BitsForWordCount(count int) (int, error) {
+	switch count {
+	case 0, 12:
+		return Mnemonic12Words, nil
+	case 24:
+		return Mnemonic24Words, nil
 	}
-	return words, nil
+	return 0, errors.New("mnemonic must be 12 or 24 words")
 }
 
-func joinWords(words []string) string {
-	result := ""
-	for i, word := range words {
-		if i > 0 {
-			result += " "
-		}
-		result += word
-	}
-	return result
+func ValidateMnemonic(words []string) bool {
+	return CheckMnemonic(words, "").Valid()
 }
 
-func ValidateMnemonic(words []string) bool {
-	mnemonic := joinWords(words)
-	return bip39.IsMnemonicValid(mnemonic)
+func mnemonicSeed(words []string, passphrase []byte) []byte {
+	mnemonic := []byte(norm.NFKD.String(strings.Join(words, " ")))
+	defer wipe(mnemonic)
+	salt := norm.NFKD.Append([]byte("mnemonic"), passphrase...)
+	defer wipe(salt)
+	return pbkdf2.Key(mnemonic, salt, 2048, 64, sha512.New)
 }
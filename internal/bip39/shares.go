@@ -0,0 +1,233 @@
+package bip39
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	MaxShares    = 16
+	MinThreshold = 2
+
+	shareSetBits = 3
+)
+
+type share struct {
+	id        int
+	threshold int
+	index     byte
+	language  string
+	value     []byte
+}
+
+func SplitMnemonic(words []string, threshold, count int) ([][]string, error) {
+	report := CheckMnemonic(words, "")
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	if threshold < MinThreshold || count < threshold || count > MaxShares {
+		return nil, fmt.Errorf("threshold must be between %d and the share count, and at most %d shares are allowed", MinThreshold, MaxShares)
+	}
+	list, err := lookupWordList(report.Language)
+	if err != nil {
+		return nil, err
+	}
+	entropy := mnemonicEntropy(list, report.Words)
+	defer wipe(entropy)
+
+	var idByte [1]byte
+	if _, err := rand.Read(idByte[:]); err != nil {
+		return nil, err
+	}
+	id := int(idByte[0]) & (1<<shareSetBits - 1)
+
+	coefficients := make([]byte, len(entropy)*(threshold-1))
+	if _, err := rand.Read(coefficients); err != nil {
+		return nil, err
+	}
+	defer wipe(coefficients)
+
+	result := make([][]string, count)
+	for i := 0; i < count; i++ {
+		x := byte(i + 1)
+		value := make([]byte, len(entropy))
+		for b := range entropy {
+			y := byte(0)
+			for d := threshold - 2; d >= 0; d-- {
+				y = gfMul(y, x) ^ coefficients[b*(threshold-1)+d]
+			}
+			value[b] = gfMul(y, x) ^ entropy[b]
+		}
+		s := share{id: id, threshold: threshold, index: x, language: report.Language, value: value}
+		result[i], err = s.words()
+		wipe(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func CombineShares(shares [][]string) ([]string, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("at least one share required")
+	}
+
+	parsed := make([]share, 0, len(shares))
+	seen := make(map[byte]bool)
+	for i, words := range shares {
+		s, err := parseShare(words)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", i+1, err)
+		}
+		if len(parsed) > 0 {
+			first := parsed[0]
+			if s.id != first.id || s.threshold != first.threshold || s.language != first.language || len(s.value) != len(first.value) {
+				return nil, fmt.Errorf("share %d belongs to a different set", i+1)
+			}
+		}
+		if seen[s.index] {
+			return nil, fmt.Errorf("share %d is a duplicate", i+1)
+		}
+		seen[s.index] = true
+		parsed = append(parsed, s)
+	}
+	defer func() {
+		for _, s := range parsed {
+			wipe(s.value)
+		}
+	}()
+
+	threshold := parsed[0].threshold
+	if len(parsed) < threshold {
+		return nil, fmt.Errorf("%d of %d required shares provided", len(parsed), threshold)
+	}
+	parsed = parsed[:threshold]
+
+	secret := make([]byte, len(parsed[0].value))
+	defer wipe(secret)
+	for i, si := range parsed {
+		basis := byte(1)
+		for j, sj := range parsed {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfMul(sj.index, gfInverse(sj.index^si.index)))
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(si.value[b], basis)
+		}
+	}
+	return entropyToMnemonic(secret, parsed[0].language)
+}
+
+func ValidateShare(words []string) error {
+	s, err := parseShare(words)
+	if err != nil {
+		return err
+	}
+	wipe(s.value)
+	return nil
+}
+
+func (s share) words() ([]string, error) {
+	list, err := lookupWordList(s.language)
+	if err != nil {
+		return nil, err
+	}
+	body, err := entropyToMnemonic(s.value, s.language)
+	if err != nil {
+		return nil, err
+	}
+	header := (s.threshold-1)<<7 | int(s.index-1)<<shareSetBits | s.id
+	return append([]string{list.words[header]}, body...), nil
+}
+
+func parseShare(words []string) (share, error) {
+	if len(words) < 2 {
+		return share{}, errors.New("share is too short")
+	}
+	language := DetectLanguage(words)
+	list, err := lookupWordList(language)
+	if err != nil {
+		return share{}, err
+	}
+	header, ok := list.lookup(normalizeWord(words[0]))
+	if !ok {
+		return share{}, fmt.Errorf("word 1 (%q) is not in the %s word list", words[0], language)
+	}
+
+	report := CheckMnemonic(words[1:], language)
+	if !report.WordCountValid {
+		return share{}, errors.New("share has the wrong number of words")
+	}
+	for i := range report.UnknownWords {
+		report.UnknownWords[i].Position++
+	}
+	for i := range report.Corrections {
+		report.Corrections[i].Position++
+	}
+	if report.ChecksumWord > 0 {
+		report.ChecksumWord++
+	}
+	if err := report.Err(); err != nil {
+		return share{}, err
+	}
+
+	threshold := header>>7 + 1
+	if threshold < MinThreshold {
+		return share{}, errors.New("share header is invalid")
+	}
+	return share{
+		id:        header & (1<<shareSetBits - 1),
+		threshold: threshold,
+		index:     byte(header>>shareSetBits&0xf + 1),
+		language:  language,
+		value:     mnemonicEntropy(list, report.Words),
+	}, nil
+}
+
+func mnemonicEntropy(list *wordList, words []string) []byte {
+	totalBits := len(words) * 11
+	entropyBits := totalBits - totalBits/33
+	data := make([]byte, (totalBits+7)/8)
+	defer wipe(data)
+	for w, word := range words {
+		index, _ := list.lookup(word)
+		for bit := 0; bit < 11; bit++ {
+			if index&(1<<uint(10-bit)) != 0 {
+				pos := w*11 + bit
+				data[pos/8] |= 0x80 >> uint(pos%8)
+			}
+		}
+	}
+	return append([]byte(nil), data[:entropyBits/8]...)
+}
+
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		mask := -(b & 1)
+		p ^= a & mask
+		carry := -(a >> 7)
+		a = a<<1 ^ 0x1b&carry
+		b >>= 1
+	}
+	return p
+}
+
+func gfInverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 7; i++ {
+		a = gfMul(a, a)
+		result = gfMul(result, a)
+	}
+	return result
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,119 @@
+package bip39
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnyThresholdSharesRecoverMnemonic(t *testing.T) {
+	for _, bits := range []int{Mnemonic12Words, Mnemonic24Words} {
+		mnemonic, err := GenerateMnemonic(bits)
+		if err != nil {
+			t.Fatalf("generate mnemonic: %v", err)
+		}
+		shares, err := SplitMnemonic(mnemonic.Words, 3, 5)
+		if err != nil {
+			t.Fatalf("split: %v", err)
+		}
+		for _, share := range shares {
+			if len(share) != len(mnemonic.Words)+1 {
+				t.Fatalf("expected %d words per share, got %d", len(mnemonic.Words)+1, len(share))
+			}
+		}
+
+		for a := 0; a < 5; a++ {
+			for b := a + 1; b < 5; b++ {
+				for c := b + 1; c < 5; c++ {
+					words, err := CombineShares([][]string{shares[c], shares[a], shares[b]})
+					if err != nil {
+						t.Fatalf("combine %d,%d,%d: %v", a, b, c, err)
+					}
+					if strings.Join(words, " ") != strings.Join(mnemonic.Words, " ") {
+						t.Fatalf("shares %d,%d,%d recovered a different mnemonic", a, b, c)
+					}
+				}
+			}
+		}
+
+		if _, err := CombineShares(shares[:2]); err == nil {
+			t.Fatal("expected two of three shares to be rejected")
+		}
+		if _, err := CombineShares([][]string{shares[0], shares[0], shares[1]}); err == nil {
+			t.Fatal("expected duplicate shares to be rejected")
+		}
+	}
+}
+
+func TestSharesKeepTheMnemonicLanguage(t *testing.T) {
+	mnemonic, err := GenerateMnemonicInLanguage(Mnemonic12Words, nil, LanguageSpanish)
+	if err != nil {
+		t.Fatalf("generate mnemonic: %v", err)
+	}
+	shares, err := SplitMnemonic(mnemonic.Words, 2, 3)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	words, err := CombineShares([][]string{shares[2], shares[0]})
+	if err != nil {
+		t.Fatalf("combine: %v", err)
+	}
+	restored, err := RestoreFromMnemonicInLanguage(words, []byte("passphrase"), LanguageSpanish)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	original, err := RestoreFromMnemonicInLanguage(mnemonic.Words, []byte("passphrase"), LanguageSpanish)
+	if err != nil {
+		t.Fatalf("restore original: %v", err)
+	}
+	if string(restored.Seed) != string(original.Seed) {
+		t.Fatal("recombined mnemonic derives a different seed")
+	}
+}
+
+func TestSplitMnemonicRequiresThresholdOfTwo(t *testing.T) {
+	mnemonic, err := GenerateMnemonic(Mnemonic12Words)
+	if err != nil {
+		t.Fatalf("generate mnemonic: %v", err)
+	}
+	if _, err := SplitMnemonic(mnemonic.Words, 1, 3); err == nil {
+		t.Fatal("expected a threshold of one to be rejected")
+	}
+	if _, err := SplitMnemonic(mnemonic.Words, 2, MaxShares+1); err == nil {
+		t.Fatal("expected too many shares to be rejected")
+	}
+}
+
+func TestShareChecksumReportsShare(t *testing.T) {
+	words := strings.Fields("legal winner thank year wave sausage worth useful legal winner thank yellow")
+	shares, err := SplitMnemonic(words, 2, 3)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+
+	list, err := lookupWordList(LanguageEnglish)
+	if err != nil {
+		t.Fatalf("word list: %v", err)
+	}
+	corrupted := append([]string(nil), shares[1]...)
+	for _, candidate := range list.words {
+		if candidate == shares[1][5] {
+			continue
+		}
+		corrupted[5] = candidate
+		if ValidateShare(corrupted) != nil {
+			break
+		}
+	}
+	_, err = CombineShares([][]string{shares[0], corrupted})
+	if err == nil || !strings.HasPrefix(err.Error(), "share 2:") {
+		t.Fatalf("expected error for share 2, got %v", err)
+	}
+	misspelled := append([]string(nil), shares[2]...)
+	misspelled[3] = "zzzz"
+	if err := ValidateShare(misspelled); err == nil || !strings.Contains(err.Error(), "word 4") {
+		t.Fatalf("expected the misspelled word to be reported by position, got %v", err)
+	}
+	if err := ValidateShare(shares[2][:len(shares[2])-1]); err == nil {
+		t.Fatal("expected truncated share to fail validation")
+	}
+}
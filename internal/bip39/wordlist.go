@@ -0,0 +1,368 @@
+package bip39
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	LanguageEnglish            = "english"
+	LanguageFrench             = "french"
+	LanguageItalian            = "italian"
+	LanguageSpanish            = "spanish"
+	LanguageCzech              = "czech"
+	LanguageJapanese           = "japanese"
+	LanguageKorean             = "korean"
+	LanguageChineseSimplified  = "chinese_simplified"
+	LanguageChineseTraditional = "chinese_traditional"
+
+	maxSuggestions  = 3
+	maxEditDistance = 2
+)
+
+var languageOrder = []string{
+	LanguageEnglish,
+	LanguageFrench,
+	LanguageItalian,
+	LanguageSpanish,
+	LanguageCzech,
+	LanguageJapanese,
+	LanguageKorean,
+	LanguageChineseSimplified,
+	LanguageChineseTraditional,
+}
+
+type wordList struct {
+	words       []string
+	index       map[string]int
+	folded      []string
+	foldedIndex map[string]int
+}
+
+var wordListSources = map[string][]string{
+	LanguageEnglish:            wordlists.English,
+	LanguageFrench:             wordlists.French,
+	LanguageItalian:            wordlists.Italian,
+	LanguageSpanish:            wordlists.Spanish,
+	LanguageCzech:              wordlists.Czech,
+	LanguageJapanese:           wordlists.Japanese,
+	LanguageKorean:             wordlists.Korean,
+	LanguageChineseSimplified:  wordlists.ChineseSimplified,
+	LanguageChineseTraditional: wordlists.ChineseTraditional,
+}
+
+var loadedWordLists = map[string]*wordList{}
+
+func Languages() []string {
+	return append([]string(nil), languageOrder...)
+}
+
+func ValidLanguage(language string) bool {
+	_, ok := wordListSources[language]
+	return ok
+}
+
+func lookupWordList(language string) (*wordList, error) {
+	if len(language) == 0 {
+		language = LanguageEnglish
+	}
+	if list, ok := loadedWordLists[language]; ok {
+		return list, nil
+	}
+	source, ok := wordListSources[language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported word list language %q", language)
+	}
+	list := &wordList{
+		words:       make([]string, len(source)),
+		index:       make(map[string]int, len(source)),
+		folded:      make([]string, len(source)),
+		foldedIndex: make(map[string]int, len(source)),
+	}
+	for i, word := range source {
+		normalized := normalizeWord(word)
+		list.words[i] = normalized
+		list.index[normalized] = i
+		list.folded[i] = foldWord(normalized)
+		if _, ok := list.foldedIndex[list.folded[i]]; ok {
+			list.foldedIndex[list.folded[i]] = -1
+		} else {
+			list.foldedIndex[list.folded[i]] = i
+		}
+	}
+	loadedWordLists[language] = list
+	return list, nil
+}
+
+func init() {
+	for _, language := range languageOrder {
+		if _, err := lookupWordList(language); err != nil {
+			panic(err)
+		}
+	}
+}
+
+type WordIssue struct {
+	Position    int
+	Word        string
+	Suggestions []string
+}
+
+type MnemonicReport struct {
+	Language       string
+	Words          []string
+	WordCountValid bool
+	UnknownWords   []WordIssue
+	ChecksumValid  bool
+	ChecksumWord   int
+	Corrections    []WordIssue
+}
+
+func (r *MnemonicReport) Valid() bool {
+	return r.WordCountValid && len(r.UnknownWords) == 0 && r.ChecksumValid
+}
+
+func (r *MnemonicReport) Err() error {
+	switch {
+	case !r.WordCountValid:
+		return fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, got %d", len(r.Words))
+	case len(r.UnknownWords) > 0:
+		issue := r.UnknownWords[0]
+		if len(issue.Suggestions) == 0 {
+			return fmt.Errorf("word %d (%q) is not in the %s word list", issue.Position, issue.Word, r.Language)
+		}
+		return fmt.Errorf("word %d (%q) is not in the %s word list; did you mean %s?", issue.Position, issue.Word, r.Language, quoteWords(issue.Suggestions))
+	case !r.ChecksumValid:
+		if len(r.Corrections) > 0 {
+			issue := r.Corrections[0]
+			return fmt.Errorf("mnemonic checksum does not match; word %d (%q) could be %s", issue.Position, issue.Word, quoteWords(issue.Suggestions))
+		}
+		return fmt.Errorf("mnemonic checksum does not match; one of the words is wrong and word %d carries the checksum", r.ChecksumWord)
+	}
+	return nil
+}
+
+func CheckMnemonic(words []string, language string) *MnemonicReport {
+	normalized := make([]string, len(words))
+	for i, word := range words {
+		normalized[i] = normalizeWord(word)
+	}
+	if len(language) == 0 {
+		language = DetectLanguage(normalized)
+	}
+	report := &MnemonicReport{
+		Language:       language,
+		Words:          normalized,
+		WordCountValid: len(words) >= 12 && len(words) <= 24 && len(words)%3 == 0,
+	}
+	list, err := lookupWordList(language)
+	if err != nil {
+		return report
+	}
+
+	indices := make([]int, len(normalized))
+	for i, word := range normalized {
+		index, ok := list.lookup(word)
+		if !ok {
+			report.UnknownWords = append(report.UnknownWords, WordIssue{
+				Position:    i + 1,
+				Word:        words[i],
+				Suggestions: list.nearest(word, -1),
+			})
+			continue
+		}
+		indices[i] = index
+		normalized[i] = list.words[index]
+	}
+	if !report.WordCountValid || len(report.UnknownWords) > 0 {
+		return report
+	}
+
+	report.ChecksumValid = checksumMatches(indices)
+	if report.ChecksumValid {
+		return report
+	}
+	for i, word := range normalized {
+		var fixes []string
+		original := indices[i]
+		for _, candidate := range list.nearest(word, original) {
+			indices[i] = list.index[candidate]
+			if checksumMatches(indices) {
+				fixes = append(fixes, candidate)
+			}
+		}
+		indices[i] = original
+		if len(fixes) > 0 {
+			report.Corrections = append(report.Corrections, WordIssue{Position: i + 1, Word: words[i], Suggestions: fixes})
+		}
+	}
+	if len(report.Corrections) == 0 {
+		report.ChecksumWord = len(words)
+	}
+	return report
+}
+
+func DetectLanguage(words []string) string {
+	best, bestCount := LanguageEnglish, 0
+	for _, language := range languageOrder {
+		list, _ := lookupWordList(language)
+		count := 0
+		for _, word := range words {
+			if _, ok := list.lookup(normalizeWord(word)); ok {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	return best
+}
+
+func (l *wordList) lookup(word string) (int, bool) {
+	if index, ok := l.index[word]; ok {
+		return index, true
+	}
+	index, ok := l.foldedIndex[foldWord(word)]
+	return index, ok && index >= 0
+}
+
+func (l *wordList) nearest(word string, exclude int) []string {
+	folded := foldWord(word)
+	type candidate struct {
+		index    int
+		distance int
+	}
+	var candidates []candidate
+	for i, other := range l.folded {
+		if i == exclude {
+			continue
+		}
+		distance := editDistance(folded, other, maxEditDistance)
+		if distance > maxEditDistance && len([]rune(folded)) >= 4 && strings.HasPrefix(other, string([]rune(folded)[:4])) {
+			distance = maxEditDistance
+		}
+		if distance <= maxEditDistance {
+			candidates = append(candidates, candidate{i, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].distance < candidates[b].distance })
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	out := make([]string, len(candidates))
+	for i, c := range candidates {
+		out[i] = l.words[c.index]
+	}
+	return out
+}
+
+func (l *wordList) encode(data []byte, wordCount int) []string {
+	words := make([]string, wordCount)
+	for w := range words {
+		index := 0
+		for bit := 0; bit < 11; bit++ {
+			pos := w*11 + bit
+			index = index<<1 | int(data[pos/8]>>uint(7-pos%8)&1)
+		}
+		words[w] = l.words[index]
+	}
+	return words
+}
+
+func entropyToMnemonic(entropy []byte, language string) ([]string, error) {
+	list, err := lookupWordList(language)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(entropy)
+	data := append(append([]byte(nil), entropy...), hash[0])
+	defer wipe(data)
+	bits := len(entropy)*8 + len(entropy)*8/32
+	return list.encode(data, bits/11), nil
+}
+
+func checksumMatches(indices []int) bool {
+	totalBits := len(indices) * 11
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+
+	data := make([]byte, (totalBits+7)/8)
+	defer wipe(data)
+	for w, index := range indices {
+		for bit := 0; bit < 11; bit++ {
+			if index&(1<<uint(10-bit)) != 0 {
+				pos := w*11 + bit
+				data[pos/8] |= 0x80 >> uint(pos%8)
+			}
+		}
+	}
+	hash := sha256.Sum256(data[:entropyBits/8])
+	for bit := 0; bit < checksumBits; bit++ {
+		pos := entropyBits + bit
+		got := data[pos/8] >> uint(7-pos%8) & 1
+		want := hash[bit/8] >> uint(7-bit%8) & 1
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeWord(word string) string {
+	return norm.NFKD.String(strings.ToLower(strings.TrimSpace(word)))
+}
+
+func foldWord(word string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func quoteWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = fmt.Sprintf("%q", norm.NFC.String(word))
+	}
+	return strings.Join(quoted, " or ")
+}
@@ -0,0 +1,62 @@
+package bip39
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCheckMnemonicSuggestsWordsAndDetectsLanguage(t *testing.T) {
+	words := strings.Fields("abandon abandon abandon abandon abandon abandn abandon abandon abandon abandon abandon about")
+	report := CheckMnemonic(words, "")
+	if report.Valid() || len(report.UnknownWords) != 1 {
+		t.Fatalf("expected one unknown word, got %+v", report)
+	}
+	issue := report.UnknownWords[0]
+	if issue.Position != 6 || len(issue.Suggestions) == 0 || issue.Suggestions[0] != "abandon" {
+		t.Fatalf("unexpected suggestion %+v", issue)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), `did you mean "abandon"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	words[5] = "abandon"
+	words[11] = "abandon"
+	report = CheckMnemonic(words, LanguageEnglish)
+	if report.ChecksumValid || len(report.Corrections) != 0 || report.ChecksumWord != 12 || report.Err() == nil {
+		t.Fatalf("expected checksum failure at word 12, got %+v", report)
+	}
+
+	words = strings.Fields("legal winner thank year wave sausage worth useful legal winner thank year")
+	report = CheckMnemonic(words, LanguageEnglish)
+	if report.ChecksumValid || len(report.Corrections) == 0 || report.ChecksumWord != 0 {
+		t.Fatalf("expected corrections without a checksum word, got %+v", report)
+	}
+
+	mnemonic, err := GenerateMnemonicInLanguage(Mnemonic24Words, nil, LanguageSpanish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mnemonic.Words) != 24 || mnemonic.Language != LanguageSpanish {
+		t.Fatalf("unexpected mnemonic %d words in %s", len(mnemonic.Words), mnemonic.Language)
+	}
+	restored, err := RestoreFromMnemonic(mnemonic.Words, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Language != LanguageSpanish || !bytes.Equal(restored.Seed, mnemonic.Seed) {
+		t.Fatal("restored spanish mnemonic does not match")
+	}
+
+	folded := make([]string, len(mnemonic.Words))
+	for i, word := range mnemonic.Words {
+		folded[i] = strings.ToUpper(foldWord(word))
+	}
+	restored, err = RestoreFromMnemonicInLanguage(folded, nil, LanguageSpanish)
+	if err != nil {
+		t.Fatalf("accent-free words should restore: %v", err)
+	}
+	if !bytes.Equal(restored.Seed, mnemonic.Seed) {
+		t.Fatal("accent-free words produced a different seed")
+	}
+}
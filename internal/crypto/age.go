@@ -0,0 +1,577 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	AgeDefaultScryptLogN = 18
+	AgeMaxScryptLogN     = 22
+
+	ageVersionLine    = "age-encryption.org/v1"
+	ageIdentityHRP    = "age-secret-key-"
+	ageRecipientHRP   = "age"
+	ageX25519Type     = "X25519"
+	ageScryptType     = "scrypt"
+	ageX25519Label    = "age-encryption.org/v1/X25519"
+	ageScryptLabel    = "age-encryption.org/v1/scrypt"
+	ageFileKeySize    = 16
+	ageScryptSaltSize = 16
+	ageNonceSize      = 16
+	ageChunkSize      = 64 * 1024
+	ageColumnsPerLine = 64
+	ageMaxHeaderLine  = 4096
+	ageMaxHeaderSize  = 1 << 20
+)
+
+var (
+	ErrAgeNoMatch = errors.New("no identity matched the age file")
+
+	ageBase64 = base64.RawStdEncoding.Strict()
+)
+
+type AgeIdentity struct {
+	key *ecdh.PrivateKey
+}
+
+type AgeRecipient struct {
+	key *ecdh.PublicKey
+}
+
+type ageStanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+func GenerateAgeIdentity() (*AgeIdentity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &AgeIdentity{key: key}, nil
+}
+
+func ParseAgeIdentity(encoded string) (*AgeIdentity, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(encoded))
+	if err != nil || hrp != ageIdentityHRP {
+		return nil, errors.New("invalid age identity")
+	}
+	defer WipeBytes(data)
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, errors.New("invalid age identity")
+	}
+	return &AgeIdentity{key: key}, nil
+}
+
+func (id *AgeIdentity) String() string {
+	encoded, _ := bech32Encode(ageIdentityHRP, id.key.Bytes())
+	return strings.ToUpper(encoded)
+}
+
+func (id *AgeIdentity) Recipient() *AgeRecipient {
+	return &AgeRecipient{key: id.key.PublicKey()}
+}
+
+func ParseAgeRecipient(encoded string) (*AgeRecipient, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(encoded))
+	if err != nil || hrp != ageRecipientHRP {
+		return nil, errors.New("invalid age recipient")
+	}
+	key, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, errors.New("invalid age recipient")
+	}
+	return &AgeRecipient{key: key}, nil
+}
+
+func (r *AgeRecipient) String() string {
+	encoded, _ := bech32Encode(ageRecipientHRP, r.key.Bytes())
+	return encoded
+}
+
+func EncryptAge(dst io.Writer, src io.Reader, recipients ...*AgeRecipient) error {
+	if len(recipients) == 0 {
+		return errors.New("at least one age recipient required")
+	}
+	fileKey, err := randomBytes(ageFileKeySize)
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(fileKey)
+
+	stanzas := make([]*ageStanza, 0, len(recipients))
+	for _, recipient := range recipients {
+		stanza, err := wrapAgeX25519(recipient, fileKey)
+		if err != nil {
+			return err
+		}
+		stanzas = append(stanzas, stanza)
+	}
+	return writeAge(dst, src, fileKey, stanzas)
+}
+
+func EncryptAgeWithPassphrase(dst io.Writer, src io.Reader, passphrase []byte, logN int) error {
+	if len(passphrase) == 0 {
+		return errors.New("passphrase cannot be empty")
+	}
+	if logN == 0 {
+		logN = AgeDefaultScryptLogN
+	}
+	if logN < 1 || logN > AgeMaxScryptLogN {
+		return errors.New("invalid scrypt work factor")
+	}
+	fileKey, err := randomBytes(ageFileKeySize)
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(fileKey)
+
+	salt, err := randomBytes(ageScryptSaltSize)
+	if err != nil {
+		return err
+	}
+	key, err := ageScryptKey(passphrase, salt, logN)
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(key)
+	body, err := ageSealFileKey(key, fileKey)
+	if err != nil {
+		return err
+	}
+
+	stanza := &ageStanza{
+		Type: ageScryptType,
+		Args: []string{ageBase64.EncodeToString(salt), strconv.Itoa(logN)},
+		Body: body,
+	}
+	return writeAge(dst, src, fileKey, []*ageStanza{stanza})
+}
+
+func DecryptAge(dst io.Writer, src io.Reader, identities ...*AgeIdentity) error {
+	if len(identities) == 0 {
+		return errors.New("at least one age identity required")
+	}
+	return readAge(dst, src, func(stanzas []*ageStanza) ([]byte, error) {
+		for _, stanza := range stanzas {
+			if stanza.Type == ageScryptType {
+				return nil, errors.New("age file is passphrase encrypted")
+			}
+		}
+		for _, stanza := range stanzas {
+			if stanza.Type != ageX25519Type {
+				continue
+			}
+			for _, identity := range identities {
+				fileKey, err := unwrapAgeX25519(identity, stanza)
+				if err == nil {
+					return fileKey, nil
+				}
+				if !errors.Is(err, ErrAgeNoMatch) {
+					return nil, err
+				}
+			}
+		}
+		return nil, ErrAgeNoMatch
+	})
+}
+
+func DecryptAgeWithPassphrase(dst io.Writer, src io.Reader, passphrase []byte) error {
+	return readAge(dst, src, func(stanzas []*ageStanza) ([]byte, error) {
+		var stanza *ageStanza
+		for _, candidate := range stanzas {
+			if candidate.Type == ageScryptType {
+				stanza = candidate
+			}
+		}
+		if stanza == nil {
+			return nil, ErrAgeNoMatch
+		}
+		if len(stanzas) != 1 {
+			return nil, errors.New("scrypt stanza must be the only stanza")
+		}
+		if len(stanza.Args) != 2 {
+			return nil, errors.New("invalid scrypt stanza")
+		}
+		salt, err := ageBase64.DecodeString(stanza.Args[0])
+		if err != nil || len(salt) != ageScryptSaltSize {
+			return nil, errors.New("invalid scrypt salt")
+		}
+		logN, err := parseAgeWorkFactor(stanza.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		if len(stanza.Body) != ageFileKeySize+chacha20poly1305.Overhead {
+			return nil, errors.New("invalid scrypt stanza body")
+		}
+		key, err := ageScryptKey(passphrase, salt, logN)
+		if err != nil {
+			return nil, err
+		}
+		defer WipeBytes(key)
+		fileKey, err := ageOpenFileKey(key, stanza.Body)
+		if err != nil {
+			return nil, ErrAgeNoMatch
+		}
+		return fileKey, nil
+	})
+}
+
+func wrapAgeX25519(recipient *AgeRecipient, fileKey []byte) (*ageStanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient.key)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(shared)
+
+	share := ephemeral.PublicKey().Bytes()
+	key, err := ageX25519Key(shared, share, recipient.key.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(key)
+	body, err := ageSealFileKey(key, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ageStanza{Type: ageX25519Type, Args: []string{ageBase64.EncodeToString(share)}, Body: body}, nil
+}
+
+func unwrapAgeX25519(identity *AgeIdentity, stanza *ageStanza) ([]byte, error) {
+	if len(stanza.Args) != 1 {
+		return nil, errors.New("invalid X25519 stanza")
+	}
+	share, err := ageBase64.DecodeString(stanza.Args[0])
+	if err != nil || len(share) != x25519KeyLength {
+		return nil, errors.New("invalid X25519 share")
+	}
+	if len(stanza.Body) != ageFileKeySize+chacha20poly1305.Overhead {
+		return nil, errors.New("invalid X25519 stanza body")
+	}
+	remote, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, errors.New("invalid X25519 share")
+	}
+	shared, err := identity.key.ECDH(remote)
+	if err != nil {
+		return nil, errors.New("invalid X25519 share")
+	}
+	defer WipeBytes(shared)
+
+	key, err := ageX25519Key(shared, share, identity.key.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(key)
+	fileKey, err := ageOpenFileKey(key, stanza.Body)
+	if err != nil {
+		return nil, ErrAgeNoMatch
+	}
+	return fileKey, nil
+}
+
+func ageX25519Key(shared, share, recipient []byte) ([]byte, error) {
+	salt := make([]byte, 0, len(share)+len(recipient))
+	salt = append(salt, share...)
+	salt = append(salt, recipient...)
+	return deriveHKDFKey(shared, salt, ageX25519Label)
+}
+
+func ageScryptKey(passphrase []byte, salt []byte, logN int) ([]byte, error) {
+	labeled := append([]byte(ageScryptLabel), salt...)
+	return scrypt.Key(passphrase, labeled, 1<<logN, 8, 1, chacha20poly1305.KeySize)
+}
+
+func ageSealFileKey(key, fileKey []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil), nil
+}
+
+func ageOpenFileKey(key, body []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, aead.NonceSize()), body, nil)
+}
+
+func parseAgeWorkFactor(arg string) (int, error) {
+	if len(arg) == 0 || arg[0] < '1' || arg[0] > '9' {
+		return 0, errors.New("invalid scrypt work factor")
+	}
+	for i := 1; i < len(arg); i++ {
+		if arg[i] < '0' || arg[i] > '9' {
+			return 0, errors.New("invalid scrypt work factor")
+		}
+	}
+	logN, err := strconv.Atoi(arg)
+	if err != nil || logN > AgeMaxScryptLogN {
+		return 0, errors.New("scrypt work factor is too high")
+	}
+	return logN, nil
+}
+
+func writeAge(dst io.Writer, src io.Reader, fileKey []byte, stanzas []*ageStanza) error {
+	var header bytes.Buffer
+	header.WriteString(ageVersionLine + "\n")
+	for _, stanza := range stanzas {
+		header.WriteString("-> " + stanza.Type)
+		for _, arg := range stanza.Args {
+			header.WriteString(" " + arg)
+		}
+		header.WriteByte('\n')
+		body := ageBase64.EncodeToString(stanza.Body)
+		for len(body) >= ageColumnsPerLine {
+			header.WriteString(body[:ageColumnsPerLine] + "\n")
+			body = body[ageColumnsPerLine:]
+		}
+		header.WriteString(body + "\n")
+	}
+	header.WriteString("---")
+	mac, err := ageHeaderMAC(fileKey, header.Bytes())
+	if err != nil {
+		return err
+	}
+	header.WriteString(" " + ageBase64.EncodeToString(mac) + "\n")
+
+	nonce, err := randomBytes(ageNonceSize)
+	if err != nil {
+		return err
+	}
+	header.Write(nonce)
+	if _, err := dst.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	aead, err := agePayloadAEAD(fileKey, nonce)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReaderSize(src, ageChunkSize)
+	chunk := make([]byte, ageChunkSize)
+	defer WipeBytes(chunk)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := n < len(chunk)
+		if !last {
+			if _, err := reader.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+		sealed := aead.Seal(nil, agePayloadNonce(counter, last), chunk[:n], nil)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func readAge(dst io.Writer, src io.Reader, unwrap func([]*ageStanza) ([]byte, error)) error {
+	reader := bufio.NewReaderSize(src, ageChunkSize+chacha20poly1305.Overhead)
+	stanzas, headerBytes, mac, err := parseAgeHeader(reader)
+	if err != nil {
+		return err
+	}
+	fileKey, err := unwrap(stanzas)
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(fileKey)
+	if len(fileKey) != ageFileKeySize {
+		return errors.New("invalid age file key")
+	}
+
+	expected, err := ageHeaderMAC(fileKey, headerBytes)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, mac) {
+		return errors.New("age header authentication failed")
+	}
+
+	nonce := make([]byte, ageNonceSize)
+	if _, err := io.ReadFull(reader, nonce); err != nil {
+		return errors.New("age payload nonce is truncated")
+	}
+	aead, err := agePayloadAEAD(fileKey, nonce)
+	if err != nil {
+		return err
+	}
+
+	chunk := make([]byte, ageChunkSize+chacha20poly1305.Overhead)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if n < chacha20poly1305.Overhead {
+			return errors.New("age payload is truncated")
+		}
+		last := n < len(chunk)
+		if !last {
+			if _, err := reader.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+		plaintext, err := aead.Open(chunk[:0], agePayloadNonce(counter, last), chunk[:n], nil)
+		if err != nil {
+			return errors.New("age payload authentication failed")
+		}
+		if last && len(plaintext) == 0 && counter > 0 {
+			return errors.New("age payload has an empty final chunk")
+		}
+		_, err = dst.Write(plaintext)
+		WipeBytes(plaintext)
+		if err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func parseAgeHeader(reader *bufio.Reader) ([]*ageStanza, []byte, []byte, error) {
+	var header bytes.Buffer
+	readLine := func() (string, error) {
+		raw, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull || len(raw) > ageMaxHeaderLine {
+			return "", errors.New("age header line is too long")
+		}
+		if err != nil {
+			return "", errors.New("age header is truncated")
+		}
+		if header.Len()+len(raw) > ageMaxHeaderSize {
+			return "", errors.New("age header is too large")
+		}
+		header.Write(raw)
+		return strings.TrimSuffix(string(raw), "\n"), nil
+	}
+
+	version, err := readLine()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if version != ageVersionLine {
+		return nil, nil, nil, errors.New("unsupported age version")
+	}
+
+	var stanzas []*ageStanza
+	for {
+		line, err := readLine()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if strings.HasPrefix(line, "--- ") {
+			mac, err := ageBase64.DecodeString(line[4:])
+			if err != nil || len(mac) != sha256.Size {
+				return nil, nil, nil, errors.New("invalid age header MAC")
+			}
+			macInput := header.Bytes()[:header.Len()-len(line)-1+3]
+			return stanzas, append([]byte(nil), macInput...), mac, nil
+		}
+		if !strings.HasPrefix(line, "-> ") {
+			return nil, nil, nil, errors.New("invalid age header line")
+		}
+
+		args := strings.Split(line[3:], " ")
+		for _, arg := range args {
+			if !validAgeArgument(arg) {
+				return nil, nil, nil, errors.New("invalid age stanza argument")
+			}
+		}
+		stanza := &ageStanza{Type: args[0], Args: args[1:]}
+		for {
+			bodyLine, err := readLine()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(bodyLine) > ageColumnsPerLine {
+				return nil, nil, nil, errors.New("age stanza body line is too long")
+			}
+			decoded, err := ageBase64.DecodeString(bodyLine)
+			if err != nil {
+				return nil, nil, nil, errors.New("invalid age stanza body")
+			}
+			stanza.Body = append(stanza.Body, decoded...)
+			if len(bodyLine) < ageColumnsPerLine {
+				break
+			}
+		}
+		stanzas = append(stanzas, stanza)
+	}
+}
+
+func validAgeArgument(arg string) bool {
+	if len(arg) == 0 {
+		return false
+	}
+	for i := 0; i < len(arg); i++ {
+		if arg[i] < 33 || arg[i] > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+func ageHeaderMAC(fileKey, header []byte) ([]byte, error) {
+	key, err := deriveHKDFKey(fileKey, nil, "header")
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(key)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(header)
+	return mac.Sum(nil), nil
+}
+
+func agePayloadAEAD(fileKey, nonce []byte) (cipher.AEAD, error) {
+	key, err := deriveHKDFKey(fileKey, nonce, "payload")
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(key)
+	return chacha20poly1305.New(key)
+}
+
+func agePayloadNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,174 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type ageVector struct {
+	expect     string
+	payload    string
+	identity   string
+	passphrase string
+	file       []byte
+}
+
+func loadAgeVector(t *testing.T, path string) ageVector {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read vector: %v", err)
+	}
+	reader := bufio.NewReader(bytes.NewReader(data))
+	var vector ageVector
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("vector %s has no body", path)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		key, value, _ := strings.Cut(line, ": ")
+		switch key {
+		case "expect":
+			vector.expect = value
+		case "payload":
+			vector.payload = value
+		case "identity":
+			vector.identity = value
+		case "passphrase":
+			vector.passphrase = value
+		}
+	}
+	vector.file, _ = io.ReadAll(reader)
+	return vector
+}
+
+func TestAgeKnownVectors(t *testing.T) {
+	paths, err := filepath.Glob("testdata/age/[a-z]*")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no age vectors found: %v", err)
+	}
+
+	for _, path := range paths {
+		vector := loadAgeVector(t, path)
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			var out bytes.Buffer
+			var err error
+			if vector.passphrase != "" {
+				err = DecryptAgeWithPassphrase(&out, bytes.NewReader(vector.file), []byte(vector.passphrase))
+			} else {
+				identity, parseErr := ParseAgeIdentity(vector.identity)
+				if parseErr != nil {
+					t.Fatalf("parse identity: %v", parseErr)
+				}
+				err = DecryptAge(&out, bytes.NewReader(vector.file), identity)
+			}
+
+			switch vector.expect {
+			case "success":
+				if err != nil {
+					t.Fatalf("decrypt: %v", err)
+				}
+				sum := sha256.Sum256(out.Bytes())
+				if hex.EncodeToString(sum[:]) != vector.payload {
+					t.Fatal("payload hash mismatch")
+				}
+			case "no match":
+				if !errors.Is(err, ErrAgeNoMatch) {
+					t.Fatalf("expected no match, got %v", err)
+				}
+			default:
+				if err == nil {
+					t.Fatalf("expected %s", vector.expect)
+				}
+			}
+		})
+	}
+}
+
+func TestAgeRoundTrip(t *testing.T) {
+	identity, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	parsed, err := ParseAgeIdentity(identity.String())
+	if err != nil {
+		t.Fatalf("parse identity: %v", err)
+	}
+	recipient, err := ParseAgeRecipient(parsed.Recipient().String())
+	if err != nil {
+		t.Fatalf("parse recipient: %v", err)
+	}
+
+	for _, size := range []int{0, 1, ageChunkSize, ageChunkSize + 1, 2 * ageChunkSize} {
+		plaintext := bytes.Repeat([]byte{0x42}, size)
+
+		var sealed bytes.Buffer
+		if err := EncryptAge(&sealed, bytes.NewReader(plaintext), recipient); err != nil {
+			t.Fatalf("encrypt %d: %v", size, err)
+		}
+		var opened bytes.Buffer
+		if err := DecryptAge(&opened, bytes.NewReader(sealed.Bytes()), identity); err != nil {
+			t.Fatalf("decrypt %d: %v", size, err)
+		}
+		if !bytes.Equal(opened.Bytes(), plaintext) {
+			t.Fatalf("round trip mismatch for %d bytes", size)
+		}
+	}
+
+	var sealed bytes.Buffer
+	if err := EncryptAgeWithPassphrase(&sealed, strings.NewReader("secret"), []byte("passphrase"), 10); err != nil {
+		t.Fatalf("encrypt with passphrase: %v", err)
+	}
+	var opened bytes.Buffer
+	if err := DecryptAgeWithPassphrase(&opened, bytes.NewReader(sealed.Bytes()), []byte("passphrase")); err != nil {
+		t.Fatalf("decrypt with passphrase: %v", err)
+	}
+	if opened.String() != "secret" {
+		t.Fatal("passphrase round trip mismatch")
+	}
+	if err := DecryptAgeWithPassphrase(io.Discard, bytes.NewReader(sealed.Bytes()), []byte("wrong")); !errors.Is(err, ErrAgeNoMatch) {
+		t.Fatalf("expected wrong passphrase to not match, got %v", err)
+	}
+}
+
+func TestAgeRejectsOverlongHeaderLine(t *testing.T) {
+	identity, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	header := ageVersionLine + "\n-> X25519 " + strings.Repeat("A", 2*ageChunkSize)
+	err = DecryptAge(io.Discard, strings.NewReader(header), identity)
+	if err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Fatalf("expected an overlong header line to be rejected, got %v", err)
+	}
+}
+
+func TestBech32KnownVectors(t *testing.T) {
+	for _, valid := range []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	} {
+		if _, _, err := bech32Decode(valid); err != nil {
+			t.Fatalf("%s: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"A1G7SGD8", "10a06t8", "1qzzfhee", "a12UEL5L"} {
+		if _, _, err := bech32Decode(invalid); err == nil {
+			t.Fatalf("expected %s to be rejected", invalid)
+		}
+	}
+}
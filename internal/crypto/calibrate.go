@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	minCalibrationMemory = Argon2DefaultMemory
+	maxCalibrationMemory = 4 * 1024 * 1024
+	maxCalibrationTime   = 64
+)
+
+var argon2Benchmark = func(memory, iterations uint32, threads uint8) time.Duration {
+	password := make([]byte, 32)
+	salt := make([]byte, SaltLength)
+	start := time.Now()
+	key := argon2.IDKey(password, salt, iterations, memory, threads, keyMaterialLength)
+	elapsed := time.Since(start)
+	WipeBytes(key)
+	return elapsed
+}
+
+func CalibrateKDF(targetDuration time.Duration, maxMemory uint32) (*KDFParams, error) {
+	if targetDuration <= 0 {
+		return nil, errors.New("target duration must be positive")
+	}
+	if maxMemory == 0 || maxMemory > maxCalibrationMemory {
+		maxMemory = maxCalibrationMemory
+	}
+	if maxMemory < minCalibrationMemory {
+		return nil, errors.New("memory limit is below the minimum key derivation cost")
+	}
+
+	memory := uint32(minCalibrationMemory)
+	threads := uint8(Argon2DefaultThreads)
+
+	elapsed := argon2Benchmark(memory, 1, threads)
+	for memory*2 <= maxMemory {
+		next := memory * 2
+		nextElapsed := argon2Benchmark(next, 1, threads)
+		if nextElapsed*time.Duration(argon2TimeFloor(next)) > targetDuration {
+			break
+		}
+		memory, elapsed = next, nextElapsed
+	}
+
+	iterations := uint32(1)
+	if elapsed > 0 {
+		iterations = uint32((targetDuration + elapsed/2) / elapsed)
+	}
+	if iterations > maxCalibrationTime {
+		iterations = maxCalibrationTime
+	}
+	if floor := argon2TimeFloor(memory); iterations < floor {
+		iterations = floor
+	}
+
+	return &KDFParams{
+		KDF:       KDFArgon2id,
+		Time:      iterations,
+		Memory:    memory,
+		Threads:   threads,
+		KeyLength: keyMaterialLength,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+)
+
+func withFakeBenchmark(t *testing.T, perMiBPass time.Duration) {
+	t.Helper()
+	original := argon2Benchmark
+	argon2Benchmark = func(memory, iterations uint32, threads uint8) time.Duration {
+		return time.Duration(memory/1024) * time.Duration(iterations) * perMiBPass
+	}
+	t.Cleanup(func() { argon2Benchmark = original })
+}
+
+func TestCalibrateKDFPrefersMemoryThenTime(t *testing.T) {
+	withFakeBenchmark(t, time.Millisecond)
+
+	params, err := CalibrateKDF(3*time.Second, 1024*1024)
+	if err != nil {
+		t.Fatalf("calibrate: %v", err)
+	}
+	if params.Memory != 1024*1024 {
+		t.Fatalf("expected memory to be raised to the cap, got %d KiB", params.Memory)
+	}
+	if params.Time != 3 {
+		t.Fatalf("expected 3 passes to reach the target, got %d", params.Time)
+	}
+	if err := validateParams(&KDFParams{Salt: make([]byte, SaltLength), Time: params.Time, Memory: params.Memory, Threads: params.Threads, KeyLength: params.KeyLength}); err != nil {
+		t.Fatalf("calibrated params invalid: %v", err)
+	}
+}
+
+func TestCalibrateKDFNeverWeakerThanDefaults(t *testing.T) {
+	withFakeBenchmark(t, 100*time.Millisecond)
+
+	params, err := CalibrateKDF(time.Second, 0)
+	if err != nil {
+		t.Fatalf("calibrate: %v", err)
+	}
+	if params.Memory != Argon2DefaultMemory || params.Time != Argon2DefaultTime {
+		t.Fatalf("expected default cost on slow hardware, got time=%d memory=%d", params.Time, params.Memory)
+	}
+}
+
+func TestCalibratedParamsAreNotUpgraded(t *testing.T) {
+	for _, perMiBPass := range []time.Duration{time.Millisecond, 3 * time.Millisecond, 100 * time.Millisecond} {
+		withFakeBenchmark(t, perMiBPass)
+		for _, maxMemory := range []uint32{Argon2DefaultMemory, 512 * 1024, 0} {
+			params, err := CalibrateKDF(time.Second, maxMemory)
+			if err != nil {
+				t.Fatalf("calibrate: %v", err)
+			}
+			if maxMemory != 0 && params.Memory > maxMemory {
+				t.Fatalf("calibrated memory %d KiB exceeds the %d KiB limit", params.Memory, maxMemory)
+			}
+			kdf, err := LookupKDF(params.KDF)
+			if err != nil {
+				t.Fatalf("lookup: %v", err)
+			}
+			if next, changed := kdf.Upgrade(params); changed {
+				t.Fatalf("calibrated params time=%d memory=%d upgraded to time=%d memory=%d", params.Time, params.Memory, next.Time, next.Memory)
+			}
+			if params.Memory > Argon2DefaultMemory && argon2Benchmark(params.Memory, params.Time, params.Threads) > time.Second+argon2Benchmark(params.Memory, 1, params.Threads)/2 {
+				t.Fatalf("calibrated params time=%d memory=%d overshoot the target", params.Time, params.Memory)
+			}
+		}
+	}
+}
+
+func TestCalibrateKDFRejectsMemoryLimitBelowDefaults(t *testing.T) {
+	withFakeBenchmark(t, time.Millisecond)
+
+	if _, err := CalibrateKDF(time.Second, Argon2DefaultMemory/4); err == nil {
+		t.Fatal("expected a memory limit below the default cost to be rejected")
+	}
+}
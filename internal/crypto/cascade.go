@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"errors"
@@ -18,13 +20,25 @@ const (
 	AESSerpent
 	AESTwofish
 	AESTwofishSerpent
+	XChaCha20
+	XChaCha20AES
+	XChaCha20Serpent
 )
 
 const streamNonceSeedSize = 32
 
+const (
+	commitmentSaltSize = 32
+	cascadeKeyLabel    = "micrypt/v2/cascade"
+)
+
+var streamHeaderMagic = []byte("MCST")
+
 type CascadeCipher struct {
-	mode    CascadeMode
-	ciphers []*Cipher
+	mode             CascadeMode
+	ciphers          []*Cipher
+	committedCiphers []*Cipher
+	commitKey        []byte
 }
 
 func NewCascadeCipher(mode CascadeMode, masterKey []byte) (*CascadeCipher, error) {
@@ -89,13 +103,67 @@ func NewCascadeCipher(mode CascadeMode, masterKey []byte) (*CascadeCipher, error
 		}
 		ciphers = append(ciphers, cipher1, cipher2, cipher3)
 
+	case XChaCha20:
+		cipher, err := NewCipher(XChaCha20Poly1305, deriveSubKey(masterKey, 8))
+		if err != nil {
+			return nil, err
+		}
+		ciphers = append(ciphers, cipher)
+
+	case XChaCha20AES:
+		key1 := deriveSubKey(masterKey, 9)
+		key2 := deriveSubKey(masterKey, 10)
+
+		cipher1, err := NewCipher(XChaCha20Poly1305, key1)
+		if err != nil {
+			return nil, err
+		}
+		cipher2, err := NewCipher(AES256GCM, key2)
+		if err != nil {
+			return nil, err
+		}
+		ciphers = append(ciphers, cipher1, cipher2)
+
+	case XChaCha20Serpent:
+		key1 := deriveSubKey(masterKey, 11)
+		key2 := deriveSubKey(masterKey, 12)
+
+		cipher1, err := NewCipher(XChaCha20Poly1305, key1)
+		if err != nil {
+			return nil, err
+		}
+		cipher2, err := NewCipher(Serpent256GCM, key2)
+		if err != nil {
+			return nil, err
+		}
+		ciphers = append(ciphers, cipher1, cipher2)
+
 	default:
 		return nil, errors.New("unsupported cascade mode")
 	}
 
+	committed := make([]*Cipher, len(ciphers))
+	for i, legacy := range ciphers {
+		layerKey, err := deriveCascadeKey(masterKey, mode, "layer/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		committed[i], err = NewCipher(legacy.Type, layerKey)
+		WipeBytes(layerKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	commitKey, err := deriveCascadeKey(masterKey, mode, "commitment")
+	if err != nil {
+		return nil, err
+	}
+
 	return &CascadeCipher{
-		mode:    mode,
-		ciphers: ciphers,
+		mode:             mode,
+		ciphers:          ciphers,
+		committedCiphers: committed,
+		commitKey:        commitKey,
 	}, nil
 }
 
@@ -124,29 +192,44 @@ func (cc *CascadeCipher) Decrypt(ciphertext []byte) ([]byte, error) {
 }
 
 func (cc *CascadeCipher) EncryptStream(plaintext io.Reader, ciphertext io.Writer) error {
-	nonces, err := cc.prepareNonces()
+	return cc.EncryptStreamVersion(plaintext, ciphertext, StreamVersion1, nil)
+}
+
+func (cc *CascadeCipher) EncryptStreamVersion(plaintext io.Reader, ciphertext io.Writer, version int, associatedData []byte) error {
+	if version != StreamVersion1 {
+		header, err := cc.writeStreamHeader(ciphertext, version)
+		if err != nil {
+			return err
+		}
+		if version >= StreamVersion4 {
+			associatedData = append(header, associatedData...)
+		}
+	}
+
+	ciphers := cc.streamLayers(version)
+	nonces, err := cc.prepareNonces(ciphers)
 	if err != nil {
 		return err
 	}
 
-	if len(cc.ciphers) == 1 {
-		return cc.ciphers[0].EncryptStreamWithNonce(plaintext, ciphertext, nonces[0])
+	if len(ciphers) == 1 {
+		return ciphers[0].EncryptStreamVersion(plaintext, ciphertext, nonces[0], version, associatedData)
 	}
 
-	errCh := make(chan error, len(cc.ciphers))
+	errCh := make(chan error, len(ciphers))
 	var wg sync.WaitGroup
 
 	currentReader := plaintext
 
-	for i := 0; i < len(cc.ciphers)-1; i++ {
+	for i := 0; i < len(ciphers)-1; i++ {
 		pr, pw := io.Pipe()
-		cipher := cc.ciphers[i]
+		cipher := ciphers[i]
 		baseNonce := append([]byte(nil), nonces[i]...)
 
 		wg.Add(1)
 		go func(c *Cipher, reader io.Reader, writer *io.PipeWriter, nonce []byte) {
 			defer wg.Done()
-			localErr := c.EncryptStreamWithNonce(reader, writer, nonce)
+			localErr := c.EncryptStreamVersion(reader, writer, nonce, version, associatedData)
 			writer.CloseWithError(localErr)
 			if localErr != nil {
 				errCh <- localErr
@@ -156,9 +239,9 @@ func (cc *CascadeCipher) EncryptStream(plaintext io.Reader, ciphertext io.Writer
 		currentReader = pr
 	}
 
-	lastCipher := cc.ciphers[len(cc.ciphers)-1]
+	lastCipher := ciphers[len(ciphers)-1]
 	lastNonce := append([]byte(nil), nonces[len(nonces)-1]...)
-	finalErr := lastCipher.EncryptStreamWithNonce(currentReader, ciphertext, lastNonce)
+	finalErr := lastCipher.EncryptStreamVersion(currentReader, ciphertext, lastNonce, version, associatedData)
 	if pr, ok := currentReader.(*io.PipeReader); ok {
 		pr.CloseWithError(finalErr)
 	}
@@ -180,23 +263,39 @@ func (cc *CascadeCipher) EncryptStream(plaintext io.Reader, ciphertext io.Writer
 }
 
 func (cc *CascadeCipher) DecryptStream(ciphertext io.Reader, plaintext io.Writer) error {
-	if len(cc.ciphers) == 1 {
-		return cc.ciphers[0].DecryptStream(ciphertext, plaintext)
+	return cc.DecryptStreamVersion(ciphertext, plaintext, StreamVersion1, nil)
+}
+
+func (cc *CascadeCipher) DecryptStreamVersion(ciphertext io.Reader, plaintext io.Writer, version int, associatedData []byte) error {
+	if version != StreamVersion1 {
+		header, err := cc.readStreamHeader(ciphertext, version)
+		if err != nil {
+			return err
+		}
+		if version >= StreamVersion4 {
+			associatedData = append(header, associatedData...)
+		}
 	}
 
-	errCh := make(chan error, len(cc.ciphers))
+	ciphers := cc.streamLayers(version)
+
+	if len(ciphers) == 1 {
+		return ciphers[0].DecryptStreamVersion(ciphertext, plaintext, version, associatedData)
+	}
+
+	errCh := make(chan error, len(ciphers))
 	var wg sync.WaitGroup
 
 	currentReader := ciphertext
 
-	for i := len(cc.ciphers) - 1; i > 0; i-- {
+	for i := len(ciphers) - 1; i > 0; i-- {
 		pr, pw := io.Pipe()
-		cipher := cc.ciphers[i]
+		cipher := ciphers[i]
 
 		wg.Add(1)
 		go func(c *Cipher, reader io.Reader, writer *io.PipeWriter) {
 			defer wg.Done()
-			localErr := c.DecryptStream(reader, writer)
+			localErr := c.DecryptStreamVersion(reader, writer, version, associatedData)
 			writer.CloseWithError(localErr)
 			if localErr != nil {
 				errCh <- localErr
@@ -206,7 +305,7 @@ func (cc *CascadeCipher) DecryptStream(ciphertext io.Reader, plaintext io.Writer
 		currentReader = pr
 	}
 
-	finalErr := cc.ciphers[0].DecryptStream(currentReader, plaintext)
+	finalErr := ciphers[0].DecryptStreamVersion(currentReader, plaintext, version, associatedData)
 	if pr, ok := currentReader.(*io.PipeReader); ok {
 		pr.CloseWithError(finalErr)
 	}
@@ -227,14 +326,69 @@ func (cc *CascadeCipher) DecryptStream(ciphertext io.Reader, plaintext io.Writer
 	return nil
 }
 
-func (cc *CascadeCipher) prepareNonces() ([][]byte, error) {
+func (cc *CascadeCipher) streamLayers(version int) []*Cipher {
+	if version >= StreamVersion4 {
+		return cc.committedCiphers
+	}
+	return cc.ciphers
+}
+
+func (cc *CascadeCipher) writeStreamHeader(w io.Writer, version int) ([]byte, error) {
+	header := append(append([]byte(nil), streamHeaderMagic...), byte(version))
+	if version >= StreamVersion4 {
+		salt := make([]byte, commitmentSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		header = append(header, salt...)
+		header = append(header, cc.commitmentTag(header)...)
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func (cc *CascadeCipher) readStreamHeader(r io.Reader, version int) ([]byte, error) {
+	header := make([]byte, len(streamHeaderMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.New("missing stream header")
+	}
+	if !bytes.Equal(header[:len(streamHeaderMagic)], streamHeaderMagic) {
+		return nil, errors.New("invalid stream header")
+	}
+	if int(header[len(streamHeaderMagic)]) != version {
+		return nil, errors.New("stream version mismatch")
+	}
+	if version < StreamVersion4 {
+		return header, nil
+	}
+
+	commitment := make([]byte, commitmentSaltSize+sha256.Size)
+	if _, err := io.ReadFull(r, commitment); err != nil {
+		return nil, errors.New("missing key commitment")
+	}
+	header = append(header, commitment[:commitmentSaltSize]...)
+	if !hmac.Equal(cc.commitmentTag(header), commitment[commitmentSaltSize:]) {
+		return nil, errors.New("key commitment mismatch")
+	}
+	return append(header, commitment[commitmentSaltSize:]...), nil
+}
+
+func (cc *CascadeCipher) commitmentTag(header []byte) []byte {
+	mac := hmac.New(sha256.New, cc.commitKey)
+	mac.Write(header)
+	return mac.Sum(nil)
+}
+
+func (cc *CascadeCipher) prepareNonces(ciphers []*Cipher) ([][]byte, error) {
 	seed := make([]byte, streamNonceSeedSize)
 	if _, err := rand.Read(seed); err != nil {
 		return nil, err
 	}
 
-	nonces := make([][]byte, len(cc.ciphers))
-	for i, cipher := range cc.ciphers {
+	nonces := make([][]byte, len(ciphers))
+	for i, cipher := range ciphers {
 		nonce, err := deriveCascadeNonce(seed, cipher, i)
 		if err != nil {
 			return nil, err
@@ -267,6 +421,16 @@ func deriveCascadeNonce(seed []byte, cipher *Cipher, index int) ([]byte, error)
 	return nonce, nil
 }
 
+func deriveCascadeKey(masterKey []byte, mode CascadeMode, label string) ([]byte, error) {
+	info := cascadeKeyLabel + "/" + strconv.Itoa(int(mode)) + "/" + label
+	reader := hkdf.New(sha256.New, masterKey, nil, []byte(info))
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 func deriveSubKey(masterKey []byte, index int) []byte {
 	h := sha256.New()
 	h.Write(masterKey)
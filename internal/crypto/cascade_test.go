@@ -0,0 +1,87 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCascadeStreamRoundTrip(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{0x5a}, 32)
+	payload := bytes.Repeat([]byte("cascade"), 3*chunkSize/7+11)
+	modes := []CascadeMode{
+		SingleCipher,
+		AESSerpent,
+		AESTwofish,
+		AESTwofishSerpent,
+		XChaCha20,
+		XChaCha20AES,
+		XChaCha20Serpent,
+	}
+
+	for _, mode := range modes {
+		cc, err := NewCascadeCipher(mode, masterKey)
+		if err != nil {
+			t.Fatalf("NewCascadeCipher(%d) error: %v", mode, err)
+		}
+
+		var encrypted bytes.Buffer
+		if err := cc.EncryptStream(bytes.NewReader(payload), &encrypted); err != nil {
+			t.Fatalf("EncryptStream failed for mode %d: %v", mode, err)
+		}
+		var decrypted bytes.Buffer
+		if err := cc.DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+			t.Fatalf("DecryptStream failed for mode %d: %v", mode, err)
+		}
+		if !bytes.Equal(payload, decrypted.Bytes()) {
+			t.Fatalf("DecryptStream mismatch for mode %d", mode)
+		}
+
+		tampered := append([]byte(nil), encrypted.Bytes()...)
+		tampered[len(tampered)/2] ^= 0x01
+		if err := cc.DecryptStream(bytes.NewReader(tampered), &bytes.Buffer{}); err == nil {
+			t.Fatalf("expected tampered stream to fail for mode %d", mode)
+		}
+	}
+}
+
+func TestXChaChaCascadeModesUseXChaChaLayer(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{0x21}, 32)
+	for _, mode := range []CascadeMode{XChaCha20, XChaCha20AES, XChaCha20Serpent} {
+		cc, err := NewCascadeCipher(mode, masterKey)
+		if err != nil {
+			t.Fatalf("NewCascadeCipher(%d) error: %v", mode, err)
+		}
+		if cc.ciphers[0].Type != XChaCha20Poly1305 {
+			t.Fatalf("expected innermost layer of mode %d to be XChaCha20-Poly1305", mode)
+		}
+	}
+}
+
+func TestCommittedStreamRejectsOtherKeys(t *testing.T) {
+	payload := []byte("committed payload")
+	for _, mode := range []CascadeMode{SingleCipher, AESTwofishSerpent, XChaCha20Serpent} {
+		owner, err := NewCascadeCipher(mode, bytes.Repeat([]byte{0x01}, 32))
+		if err != nil {
+			t.Fatalf("NewCascadeCipher(%d) error: %v", mode, err)
+		}
+		other, err := NewCascadeCipher(mode, bytes.Repeat([]byte{0x02}, 32))
+		if err != nil {
+			t.Fatalf("NewCascadeCipher(%d) error: %v", mode, err)
+		}
+
+		var encrypted bytes.Buffer
+		if err := owner.EncryptStreamVersion(bytes.NewReader(payload), &encrypted, StreamVersion4, nil); err != nil {
+			t.Fatalf("encrypt mode %d: %v", mode, err)
+		}
+		err = other.DecryptStreamVersion(bytes.NewReader(encrypted.Bytes()), &bytes.Buffer{}, StreamVersion4, nil)
+		if err == nil || err.Error() != "key commitment mismatch" {
+			t.Fatalf("expected key commitment mismatch for mode %d, got %v", mode, err)
+		}
+
+		tampered := append([]byte(nil), encrypted.Bytes()...)
+		tampered[len(streamHeaderMagic)+1] ^= 0x80
+		if err := owner.DecryptStreamVersion(bytes.NewReader(tampered), &bytes.Buffer{}, StreamVersion4, nil); err == nil {
+			t.Fatalf("expected tampered commitment salt to be rejected for mode %d", mode)
+		}
+	}
+}
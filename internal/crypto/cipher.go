@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bufio"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -24,6 +25,16 @@ const (
 
 const chunkSize = 64 * 1024
 
+const (
+	StreamVersion1       = 1
+	StreamVersion2       = 2
+	StreamVersion3       = 3
+	StreamVersion4       = 4
+	CurrentStreamVersion = StreamVersion4
+
+	streamChunkAADLength = 1 + 8 + 1
+)
+
 type Cipher struct {
 	Type CipherType
 	aead cipher.AEAD
@@ -127,11 +138,30 @@ func (c *Cipher) EncryptStream(plaintext io.Reader, ciphertext io.Writer) error
 }
 
 func (c *Cipher) DecryptStream(ciphertext io.Reader, plaintext io.Writer) error {
+	return c.DecryptStreamVersion(ciphertext, plaintext, StreamVersion1, nil)
+}
+
+func (c *Cipher) DecryptStreamVersion(ciphertext io.Reader, plaintext io.Writer, version int, associatedData []byte) error {
+	if err := checkStreamAssociatedData(version, associatedData); err != nil {
+		return err
+	}
+
 	baseNonce := make([]byte, c.aead.NonceSize())
 	if _, err := io.ReadFull(ciphertext, baseNonce); err != nil {
 		return err
 	}
 
+	switch version {
+	case StreamVersion1:
+		return c.decryptLegacyChunks(ciphertext, plaintext, baseNonce)
+	case StreamVersion2, StreamVersion3, StreamVersion4:
+		return c.decryptFramedChunks(ciphertext, plaintext, baseNonce, version, associatedData)
+	default:
+		return errors.New("unsupported stream version")
+	}
+}
+
+func (c *Cipher) decryptLegacyChunks(ciphertext io.Reader, plaintext io.Writer, baseNonce []byte) error {
 	chunkNum := uint64(0)
 	chunkLenBuf := make([]byte, 4)
 
@@ -171,16 +201,76 @@ func (c *Cipher) DecryptStream(ciphertext io.Reader, plaintext io.Writer) error
 	return nil
 }
 
+func (c *Cipher) decryptFramedChunks(ciphertext io.Reader, plaintext io.Writer, baseNonce []byte, version int, associatedData []byte) error {
+	reader := bufio.NewReader(ciphertext)
+	chunkLenBuf := make([]byte, 4)
+
+	for chunkNum := uint64(0); ; chunkNum++ {
+		if _, err := io.ReadFull(reader, chunkLenBuf); err != nil {
+			if err == io.EOF {
+				return errors.New("stream is truncated")
+			}
+			return err
+		}
+
+		chunkLen := binary.LittleEndian.Uint32(chunkLenBuf)
+		if chunkLen < uint32(c.aead.Overhead()) || chunkLen > chunkSize+uint32(c.aead.Overhead()) {
+			return errors.New("invalid chunk size")
+		}
+
+		encryptedChunk := make([]byte, chunkLen)
+		if _, err := io.ReadFull(reader, encryptedChunk); err != nil {
+			return err
+		}
+
+		_, peekErr := reader.Peek(1)
+		if peekErr != nil && peekErr != io.EOF {
+			return peekErr
+		}
+		final := peekErr == io.EOF
+
+		chunkNonce := deriveChunkNonce(baseNonce, chunkNum)
+		decrypted, err := c.aead.Open(nil, chunkNonce, encryptedChunk, streamChunkAAD(version, chunkNum, final, associatedData))
+		if err != nil {
+			if final {
+				return errors.New("stream is truncated or corrupted")
+			}
+			return err
+		}
+
+		if _, err := plaintext.Write(decrypted); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
 func (c *Cipher) EncryptStreamWithNonce(plaintext io.Reader, ciphertext io.Writer, baseNonce []byte) error {
+	return c.EncryptStreamVersion(plaintext, ciphertext, baseNonce, StreamVersion1, nil)
+}
+
+func (c *Cipher) EncryptStreamVersion(plaintext io.Reader, ciphertext io.Writer, baseNonce []byte, version int, associatedData []byte) error {
 	if len(baseNonce) != c.aead.NonceSize() {
 		return errors.New("invalid base nonce length")
 	}
+	if err := checkStreamAssociatedData(version, associatedData); err != nil {
+		return err
+	}
 
 	if _, err := ciphertext.Write(baseNonce); err != nil {
 		return err
 	}
 
-	return c.encryptStreamChunks(plaintext, ciphertext, baseNonce)
+	switch version {
+	case StreamVersion1:
+		return c.encryptStreamChunks(plaintext, ciphertext, baseNonce)
+	case StreamVersion2, StreamVersion3, StreamVersion4:
+		return c.encryptFramedChunks(plaintext, ciphertext, baseNonce, version, associatedData)
+	default:
+		return errors.New("unsupported stream version")
+	}
 }
 
 func (c *Cipher) NonceSize() int {
@@ -221,6 +311,60 @@ func (c *Cipher) encryptStreamChunks(plaintext io.Reader, ciphertext io.Writer,
 	return nil
 }
 
+func (c *Cipher) encryptFramedChunks(plaintext io.Reader, ciphertext io.Writer, baseNonce []byte, version int, associatedData []byte) error {
+	reader := bufio.NewReader(plaintext)
+	buf := make([]byte, chunkSize)
+	chunkLen := make([]byte, 4)
+
+	for chunkNum := uint64(0); ; chunkNum++ {
+		n, err := io.ReadFull(reader, buf)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return err
+		}
+		if !final {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				final = true
+			} else if peekErr != nil {
+				return peekErr
+			}
+		}
+
+		chunkNonce := deriveChunkNonce(baseNonce, chunkNum)
+		encrypted := c.aead.Seal(nil, chunkNonce, buf[:n], streamChunkAAD(version, chunkNum, final, associatedData))
+
+		binary.LittleEndian.PutUint32(chunkLen, uint32(len(encrypted)))
+		if _, err := ciphertext.Write(chunkLen); err != nil {
+			return err
+		}
+		if _, err := ciphertext.Write(encrypted); err != nil {
+			return err
+		}
+
+		if final {
+			WipeBytes(buf)
+			return nil
+		}
+	}
+}
+
+func streamChunkAAD(version int, chunkNum uint64, final bool, associatedData []byte) []byte {
+	aad := make([]byte, streamChunkAADLength, streamChunkAADLength+len(associatedData))
+	aad[0] = byte(version)
+	binary.BigEndian.PutUint64(aad[1:9], chunkNum)
+	if final {
+		aad[9] = 1
+	}
+	return append(aad, associatedData...)
+}
+
+func checkStreamAssociatedData(version int, associatedData []byte) error {
+	if len(associatedData) > 0 && version < StreamVersion3 {
+		return errors.New("stream version does not support associated data")
+	}
+	return nil
+}
+
 func deriveChunkNonce(base []byte, chunkNum uint64) []byte {
 	nonce := append([]byte(nil), base...)
 	carry := uint64(0)
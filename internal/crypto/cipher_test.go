@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"math"
 	"testing"
@@ -82,3 +83,114 @@ func TestCipherRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func streamFrames(t *testing.T, stream []byte, nonceSize int) [][]byte {
+	t.Helper()
+	var frames [][]byte
+	for offset := nonceSize; offset < len(stream); {
+		length := int(binary.LittleEndian.Uint32(stream[offset:]))
+		frames = append(frames, stream[offset:offset+4+length])
+		offset += 4 + length
+	}
+	return frames
+}
+
+func TestStreamDetectsTruncationAndExtension(t *testing.T) {
+	c, err := NewCipher(AES256GCM, bytes.Repeat([]byte{0x11}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher error: %v", err)
+	}
+	payload := bytes.Repeat([]byte{0xab}, 2*chunkSize+chunkSize/2)
+
+	var buf bytes.Buffer
+	baseNonce := bytes.Repeat([]byte{0x22}, c.NonceSize())
+	if err := c.EncryptStreamVersion(bytes.NewReader(payload), &buf, baseNonce, CurrentStreamVersion, nil); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+	stream := buf.Bytes()
+	frames := streamFrames(t, stream, c.NonceSize())
+	if len(frames) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(frames))
+	}
+
+	truncated := stream[:len(stream)-len(frames[2])]
+	if err := c.DecryptStreamVersion(bytes.NewReader(truncated), &bytes.Buffer{}, CurrentStreamVersion, nil); err == nil {
+		t.Fatal("expected truncated stream to be rejected")
+	}
+	if err := c.DecryptStreamVersion(bytes.NewReader(stream[:c.NonceSize()]), &bytes.Buffer{}, CurrentStreamVersion, nil); err == nil {
+		t.Fatal("expected stream without chunks to be rejected")
+	}
+
+	extended := append(append([]byte(nil), stream...), frames[1]...)
+	if err := c.DecryptStreamVersion(bytes.NewReader(extended), &bytes.Buffer{}, CurrentStreamVersion, nil); err == nil {
+		t.Fatal("expected extended stream to be rejected")
+	}
+
+	reordered := append([]byte(nil), stream[:c.NonceSize()]...)
+	reordered = append(reordered, frames[1]...)
+	reordered = append(reordered, frames[0]...)
+	reordered = append(reordered, frames[2]...)
+	if err := c.DecryptStreamVersion(bytes.NewReader(reordered), &bytes.Buffer{}, CurrentStreamVersion, nil); err == nil {
+		t.Fatal("expected reordered stream to be rejected")
+	}
+}
+
+func TestStreamVersionsRoundTrip(t *testing.T) {
+	cc, err := NewCascadeCipher(AESSerpent, bytes.Repeat([]byte{0x33}, 32))
+	if err != nil {
+		t.Fatalf("NewCascadeCipher error: %v", err)
+	}
+	for _, payload := range [][]byte{nil, bytes.Repeat([]byte{0x01}, chunkSize)} {
+		for _, version := range []int{StreamVersion1, StreamVersion2, StreamVersion3, StreamVersion4} {
+			var buf bytes.Buffer
+			if err := cc.EncryptStreamVersion(bytes.NewReader(payload), &buf, version, nil); err != nil {
+				t.Fatalf("encrypt v%d: %v", version, err)
+			}
+			var out bytes.Buffer
+			if err := cc.DecryptStreamVersion(bytes.NewReader(buf.Bytes()), &out, version, nil); err != nil {
+				t.Fatalf("decrypt v%d: %v", version, err)
+			}
+			if !bytes.Equal(out.Bytes(), payload) {
+				t.Fatalf("v%d round trip mismatch", version)
+			}
+		}
+	}
+
+	var legacy bytes.Buffer
+	if err := cc.EncryptStreamVersion(bytes.NewReader([]byte("legacy")), &legacy, StreamVersion1, nil); err != nil {
+		t.Fatalf("encrypt v1: %v", err)
+	}
+	if err := cc.DecryptStreamVersion(bytes.NewReader(legacy.Bytes()), &bytes.Buffer{}, CurrentStreamVersion, nil); err == nil {
+		t.Fatal("expected legacy blob without header to be rejected as current version")
+	}
+	var out bytes.Buffer
+	if err := cc.DecryptStream(bytes.NewReader(legacy.Bytes()), &out); err != nil || out.String() != "legacy" {
+		t.Fatalf("expected DecryptStream to keep reading legacy streams: %v", err)
+	}
+}
+
+func TestStreamAssociatedDataMustMatch(t *testing.T) {
+	cc, err := NewCascadeCipher(AESTwofish, bytes.Repeat([]byte{0x44}, 32))
+	if err != nil {
+		t.Fatalf("NewCascadeCipher error: %v", err)
+	}
+	payload := []byte("bound payload")
+
+	var buf bytes.Buffer
+	if err := cc.EncryptStreamVersion(bytes.NewReader(payload), &buf, StreamVersion3, []byte("entry-a")); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if err := cc.DecryptStreamVersion(bytes.NewReader(buf.Bytes()), &bytes.Buffer{}, StreamVersion3, []byte("entry-b")); err == nil {
+		t.Fatal("expected mismatched associated data to be rejected")
+	}
+	var out bytes.Buffer
+	if err := cc.DecryptStreamVersion(bytes.NewReader(buf.Bytes()), &out, StreamVersion3, []byte("entry-a")); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Fatal("round trip mismatch")
+	}
+	if err := cc.EncryptStreamVersion(bytes.NewReader(payload), &bytes.Buffer{}, StreamVersion2, []byte("entry-a")); err == nil {
+		t.Fatal("expected associated data to be refused for version 2")
+	}
+}
@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	DataKeyLength     = 32
+	dataKeyWrapLabel  = "micrypt/v2/data-key-wrap"
+	wrappedDataKeyLen = chacha20poly1305.NonceSizeX + DataKeyLength + chacha20poly1305.Overhead
+)
+
+func NewDataKey() ([]byte, error) {
+	return randomBytes(DataKeyLength)
+}
+
+func WrapDataKey(masterKey, dataKey, associatedData []byte) ([]byte, error) {
+	if len(dataKey) != DataKeyLength {
+		return nil, errors.New("invalid data key length")
+	}
+	aead, err := newDataKeyWrapper(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, associatedData), nil
+}
+
+func UnwrapDataKey(masterKey, wrapped, associatedData []byte) ([]byte, error) {
+	if len(wrapped) != wrappedDataKeyLen {
+		return nil, errors.New("invalid wrapped data key length")
+	}
+	aead, err := newDataKeyWrapper(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, associatedData)
+	if err != nil {
+		return nil, errors.New("data key unwrap failed")
+	}
+	return dataKey, nil
+}
+
+func newDataKeyWrapper(masterKey []byte) (cipher.AEAD, error) {
+	if len(masterKey) != 32 {
+		return nil, errors.New("master key must be 32 bytes")
+	}
+	kek, err := deriveHKDFKey(masterKey, nil, dataKeyWrapLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(kek)
+	return chacha20poly1305.NewX(kek)
+}
@@ -0,0 +1,160 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"sync"
+)
+
+const enclaveCanaryLength = 32
+
+var enclaveCanary = func() []byte {
+	canary := make([]byte, enclaveCanaryLength)
+	if _, err := rand.Read(canary); err != nil {
+		panic(err)
+	}
+	return canary
+}()
+
+type Enclave struct {
+	mu      sync.Mutex
+	memory  []byte
+	inner   []byte
+	data    []byte
+	locked  bool
+	guarded bool
+	sealed  bool
+	opens   int
+}
+
+func NewEnclave(size int) (*Enclave, error) {
+	if size <= 0 {
+		return nil, errors.New("enclave size must be positive")
+	}
+	memory, inner, guarded, err := allocEnclave(size + enclaveCanaryLength)
+	if err != nil {
+		return nil, err
+	}
+	e := &Enclave{
+		memory:  memory,
+		inner:   inner,
+		data:    inner[len(inner)-size:],
+		guarded: guarded,
+	}
+	canary := inner[:len(inner)-size]
+	for i := range canary {
+		canary[i] = enclaveCanary[i%enclaveCanaryLength]
+	}
+	e.locked = lockMemory(inner) == nil
+	return e, nil
+}
+
+func MoveToEnclave(src []byte) (*Enclave, error) {
+	defer WipeBytes(src)
+	e, err := NewEnclave(len(src))
+	if err != nil {
+		return nil, err
+	}
+	copy(e.data, src)
+	return e, nil
+}
+
+func (e *Enclave) Open() ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.memory == nil {
+		return nil, errors.New("enclave has been destroyed")
+	}
+	if e.sealed {
+		if err := protectMemory(e.inner, true); err != nil {
+			return nil, err
+		}
+		e.sealed = false
+	}
+	e.opens++
+	return e.data, nil
+}
+
+func (e *Enclave) Seal() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.memory == nil || e.sealed {
+		return
+	}
+	if e.opens > 0 {
+		e.opens--
+	}
+	if e.opens > 0 {
+		return
+	}
+	if !e.canaryIntact() {
+		e.destroy()
+		panic("crypto: enclave canary was overwritten")
+	}
+	if e.guarded && protectMemory(e.inner, false) == nil {
+		e.sealed = true
+	}
+}
+
+func (e *Enclave) Destroy() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.memory == nil {
+		return
+	}
+	if e.sealed && protectMemory(e.inner, true) != nil {
+		freeEnclave(e.memory)
+		e.memory, e.inner, e.data = nil, nil, nil
+		return
+	}
+	intact := e.canaryIntact()
+	e.destroy()
+	if !intact {
+		panic("crypto: enclave canary was overwritten")
+	}
+}
+
+func (e *Enclave) Size() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.data)
+}
+
+func (e *Enclave) Locked() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.memory != nil && e.locked
+}
+
+func (e *Enclave) Guarded() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.memory != nil && e.guarded
+}
+
+func (e *Enclave) Sealed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sealed
+}
+
+func (e *Enclave) canaryIntact() bool {
+	canary := e.inner[:len(e.inner)-len(e.data)]
+	intact := 1
+	for i := 0; i < len(canary); i += enclaveCanaryLength {
+		end := min(i+enclaveCanaryLength, len(canary))
+		intact &= subtle.ConstantTimeCompare(canary[i:end], enclaveCanary[:end-i])
+	}
+	return intact == 1
+}
+
+func (e *Enclave) destroy() {
+	WipeBytes(e.inner)
+	if e.locked {
+		_ = unlockMemory(e.inner)
+	}
+	freeEnclave(e.memory)
+	e.memory, e.inner, e.data = nil, nil, nil
+	e.locked, e.guarded, e.sealed, e.opens = false, false, false, 0
+}
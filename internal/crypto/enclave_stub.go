@@ -0,0 +1,16 @@
+//go:build plan9
+
+package crypto
+
+import "errors"
+
+func allocEnclave(size int) ([]byte, []byte, bool, error) {
+	memory := make([]byte, size)
+	return memory, memory, false, nil
+}
+
+func freeEnclave(memory []byte) {}
+
+func protectMemory(data []byte, writable bool) error {
+	return errors.New("memory protection is not supported on this platform")
+}
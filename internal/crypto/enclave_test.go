@@ -0,0 +1,49 @@
+package crypto
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+)
+
+func TestEnclaveSealsAndDetectsCanaryDamage(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	source := append([]byte(nil), secret...)
+	e, err := MoveToEnclave(source)
+	if err != nil {
+		t.Fatalf("move to enclave: %v", err)
+	}
+	if !bytes.Equal(source, make([]byte, len(secret))) {
+		t.Fatal("source slice was not wiped")
+	}
+
+	e.Seal()
+	if runtime.GOOS != "plan9" && (!e.Guarded() || !e.Sealed()) {
+		t.Fatal("expected enclave to be guarded and sealed while idle")
+	}
+	data, err := e.Open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(data, secret) {
+		t.Fatal("enclave contents changed across seal")
+	}
+	e.Seal()
+
+	e.Destroy()
+	if _, err := e.Open(); err == nil {
+		t.Fatal("expected destroyed enclave to refuse access")
+	}
+
+	damaged, err := NewEnclave(16)
+	if err != nil {
+		t.Fatalf("new enclave: %v", err)
+	}
+	damaged.inner[0] ^= 0xff
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected canary damage to panic")
+		}
+	}()
+	damaged.Destroy()
+}
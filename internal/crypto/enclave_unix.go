@@ -0,0 +1,31 @@
+//go:build !windows && !plan9
+
+package crypto
+
+import "golang.org/x/sys/unix"
+
+var pageSize = unix.Getpagesize()
+
+func allocEnclave(size int) ([]byte, []byte, bool, error) {
+	innerLength := (size + pageSize - 1) / pageSize * pageSize
+	memory, err := unix.Mmap(-1, 0, innerLength+2*pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	inner := memory[pageSize : pageSize+innerLength : pageSize+innerLength]
+	guarded := unix.Mprotect(memory[:pageSize], unix.PROT_NONE) == nil &&
+		unix.Mprotect(memory[pageSize+innerLength:], unix.PROT_NONE) == nil
+	return memory, inner, guarded, nil
+}
+
+func freeEnclave(memory []byte) {
+	_ = unix.Munmap(memory)
+}
+
+func protectMemory(data []byte, writable bool) error {
+	prot := unix.PROT_NONE
+	if writable {
+		prot = unix.PROT_READ | unix.PROT_WRITE
+	}
+	return unix.Mprotect(data, prot)
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package crypto
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var pageSize = windows.Getpagesize()
+
+func allocEnclave(size int) ([]byte, []byte, bool, error) {
+	innerLength := (size + pageSize - 1) / pageSize * pageSize
+	total := innerLength + 2*pageSize
+	addr, err := windows.VirtualAlloc(0, uintptr(total), windows.MEM_RESERVE|windows.MEM_COMMIT, windows.PAGE_READWRITE)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	memory := unsafe.Slice((*byte)(unsafe.Add(nil, addr)), total)
+	inner := memory[pageSize : pageSize+innerLength : pageSize+innerLength]
+	var old uint32
+	guarded := windows.VirtualProtect(addr, uintptr(pageSize), windows.PAGE_NOACCESS, &old) == nil &&
+		windows.VirtualProtect(addr+uintptr(pageSize+innerLength), uintptr(pageSize), windows.PAGE_NOACCESS, &old) == nil
+	return memory, inner, guarded, nil
+}
+
+func freeEnclave(memory []byte) {
+	_ = windows.VirtualFree(uintptr(unsafe.Pointer(&memory[0])), 0, windows.MEM_RELEASE)
+}
+
+func protectMemory(data []byte, writable bool) error {
+	prot := uint32(windows.PAGE_NOACCESS)
+	if writable {
+		prot = windows.PAGE_READWRITE
+	}
+	var old uint32
+	return windows.VirtualProtect(uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), prot, &old)
+}
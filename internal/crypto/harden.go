@@ -0,0 +1,25 @@
+package crypto
+
+import "sync"
+
+type HardeningStatus struct {
+	Platform          string
+	DumpsDisabled     bool
+	CoreDumpsDisabled bool
+	MemoryLocked      bool
+	Warnings          []string
+}
+
+var (
+	hardenOnce   sync.Once
+	hardenStatus HardeningStatus
+)
+
+func HardenProcess() HardeningStatus {
+	hardenOnce.Do(func() {
+		hardenStatus = hardenProcess()
+	})
+	status := hardenStatus
+	status.Warnings = append([]string(nil), hardenStatus.Warnings...)
+	return status
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package crypto
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+func hardenProcess() HardeningStatus {
+	status := HardeningStatus{Platform: runtime.GOOS}
+
+	if err := unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0); err != nil {
+		status.Warnings = append(status.Warnings, "could not mark the process as non-dumpable: "+err.Error())
+	} else {
+		status.DumpsDisabled = true
+	}
+
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		status.Warnings = append(status.Warnings, "could not disable core dumps: "+err.Error())
+	} else {
+		status.CoreDumpsDisabled = true
+	}
+
+	if err := unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE); err != nil {
+		switch err {
+		case unix.EPERM:
+			status.Warnings = append(status.Warnings, "not permitted to lock process memory; only key material is locked")
+		case unix.ENOMEM, unix.EAGAIN:
+			status.Warnings = append(status.Warnings, "memory lock limit is too low to lock the whole process; only key material is locked")
+		default:
+			status.Warnings = append(status.Warnings, "could not lock process memory: "+err.Error())
+		}
+	} else if !memoryLockUnbounded() {
+		// MCL_FUTURE charges all later heap growth against RLIMIT_MEMLOCK.
+		unix.Munlockall()
+		status.Warnings = append(status.Warnings, "memory lock limit would cap heap growth; only key material is locked")
+	} else {
+		status.MemoryLocked = true
+	}
+
+	return status
+}
+
+func memoryLockUnbounded() bool {
+	var memlock unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &memlock); err == nil && memlock.Cur == unix.RLIM_INFINITY {
+		return true
+	}
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return false
+	}
+	return data[unix.CAP_IPC_LOCK/32].Effective&(1<<(unix.CAP_IPC_LOCK%32)) != 0
+}
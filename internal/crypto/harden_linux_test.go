@@ -0,0 +1,33 @@
+package crypto
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestHardenProcessDisablesDumps(t *testing.T) {
+	status := HardenProcess()
+	if status.Platform != "linux" {
+		t.Fatalf("unexpected platform %q", status.Platform)
+	}
+
+	dumpable, err := unix.PrctlRetInt(unix.PR_GET_DUMPABLE, 0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("prctl: %v", err)
+	}
+	if status.DumpsDisabled != (dumpable == 0) {
+		t.Fatalf("reported dumps disabled %v, kernel reports dumpable=%d", status.DumpsDisabled, dumpable)
+	}
+
+	var core unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_CORE, &core); err != nil {
+		t.Fatalf("getrlimit: %v", err)
+	}
+	if status.CoreDumpsDisabled && (core.Cur != 0 || core.Max != 0) {
+		t.Fatalf("core limit is %d/%d after hardening", core.Cur, core.Max)
+	}
+	if !status.MemoryLocked && len(status.Warnings) == 0 {
+		t.Fatal("expected a warning when process memory is not locked")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package crypto
+
+import "runtime"
+
+func hardenProcess() HardeningStatus {
+	return HardeningStatus{
+		Platform: runtime.GOOS,
+		Warnings: []string{"process hardening is only available on Linux"},
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"fmt"
 	"io"
 
-	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
 )
 
@@ -20,46 +20,56 @@ const (
 	keyMaterialLength      = 64
 	passwordVerifierLength = 32
 	passSeedXORLength      = 32
+	vaultSecretLength      = 2 * passSeedXORLength
 	SaltLength             = 32
 	kdfInfoLabel           = "micrypt/v1/key-schedule"
-	KDFMetadataVersion     = 1
+	primarySecretLabel     = kdfInfoLabel + "/primary-secret"
+	seedSecretLabel        = kdfInfoLabel + "/seed-secret"
+	masterKeyWrapLabel     = kdfInfoLabel + "/master-wrap"
+	KDFMetadataVersion     = 3
+	legacyKDFVersion       = 1
 	maxPIMIncrement        = 1000000
 )
 
 type KDFParams struct {
+	KDF       string `json:"kdf,omitempty"`
 	Salt      []byte `json:"salt"`
-	Time      uint32 `json:"time"`
-	Memory    uint32 `json:"memory"`
-	Threads   uint8  `json:"threads"`
+	Time      uint32 `json:"time,omitempty"`
+	Memory    uint32 `json:"memory,omitempty"`
+	Threads   uint8  `json:"threads,omitempty"`
+	LogN      uint8  `json:"log_n,omitempty"`
+	R         uint32 `json:"r,omitempty"`
+	P         uint32 `json:"p,omitempty"`
 	KeyLength uint32 `json:"key_length"`
 }
 
 type KDFMetadata struct {
-	Version          int        `json:"version"`
-	Params           *KDFParams `json:"params"`
-	PasswordVerifier []byte     `json:"password_verifier"`
-	PassSeedXOR      []byte     `json:"pass_seed_xor"`
-	SeedSalt         []byte     `json:"seed_salt"`
-	HKDFSalt         []byte     `json:"hkdf_salt"`
-	KeyfileVerifier  []byte     `json:"keyfile_verifier,omitempty"`
-	KeyfileSalt      []byte     `json:"keyfile_salt,omitempty"`
-	PIM              uint32     `json:"pim,omitempty"`
+	Version           int               `json:"version"`
+	Params            *KDFParams        `json:"params"`
+	PasswordVerifier  []byte            `json:"password_verifier"`
+	PassSeedXOR       []byte            `json:"pass_seed_xor,omitempty"`
+	PassKeyXOR        []byte            `json:"pass_key_xor,omitempty"`
+	WrappedSecret     []byte            `json:"wrapped_secret,omitempty"`
+	SeedWrappedSecret []byte            `json:"seed_wrapped_secret,omitempty"`
+	WrappedMasterKey  []byte            `json:"wrapped_master_key,omitempty"`
+	SeedSalt          []byte            `json:"seed_salt"`
+	HKDFSalt          []byte            `json:"hkdf_salt"`
+	KeyfileVerifier   []byte            `json:"keyfile_verifier,omitempty"`
+	KeyfileSalt       []byte            `json:"keyfile_salt,omitempty"`
+	PIM               uint32            `json:"pim,omitempty"`
+	KeySlots          []KeySlot         `json:"key_slots,omitempty"`
+	RecoveryFile      *RecoveryFileSlot `json:"recovery_file,omitempty"`
 }
 
 type KeySchedule struct {
 	MasterKey   []byte
 	AuthKey     []byte
 	MetadataKey []byte
+	enclave     *Enclave
 }
 
 func NewKDFParams(salt []byte) *KDFParams {
-	return &KDFParams{
-		Salt:      salt,
-		Time:      Argon2DefaultTime,
-		Memory:    Argon2DefaultMemory,
-		Threads:   Argon2DefaultThreads,
-		KeyLength: keyMaterialLength,
-	}
+	return argon2idKDF{}.DefaultParams(salt)
 }
 
 func GenerateSalt() ([]byte, error) {
@@ -86,7 +96,7 @@ func GenerateSaltWithEntropy(entropySeed []byte) ([]byte, error) {
 	return hashed[:SaltLength], nil
 }
 
-func CreateKeySchedule(password string, keyfiles [][]byte, pim uint32, mnemonicSeed []byte, params *KDFParams) (*KeySchedule, *KDFMetadata, error) {
+func CreateKeySchedule(password []byte, keyfiles [][]byte, pim uint32, mnemonicSeed []byte, params *KDFParams) (*KeySchedule, *KDFMetadata, error) {
 	if len(password) == 0 && len(keyfiles) == 0 {
 		return nil, nil, errors.New("password or keyfile required")
 	}
@@ -105,26 +115,19 @@ func CreateKeySchedule(password string, keyfiles [][]byte, pim uint32, mnemonicS
 		return nil, nil, err
 	}
 	defer WipeBytes(combinedPassword)
+	defer WipeBytes(keyfileDigest)
 
-	derivedParams := cloneParams(params)
-	if pim > 0 {
-		next, err := applyPIMIncrement(derivedParams.Time, pim)
-		if err != nil {
-			return nil, nil, err
-		}
-		derivedParams.Time = next
+	passwordVerifier, pwKey, err := derivePasswordKey(combinedPassword, params, pim)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer WipeBytes(pwKey)
 
-	derived := argon2.IDKey(combinedPassword, derivedParams.Salt, derivedParams.Time, derivedParams.Memory, derivedParams.Threads, derivedParams.KeyLength)
-	if len(derived) < passwordVerifierLength+passSeedXORLength {
-		return nil, nil, errors.New("derived key material is too short")
+	secret, err := randomBytes(vaultSecretLength)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	passwordVerifier := make([]byte, passwordVerifierLength)
-	copy(passwordVerifier, derived[:passwordVerifierLength])
-
-	passKey := make([]byte, passSeedXORLength)
-	copy(passKey, derived[passwordVerifierLength:passwordVerifierLength+passSeedXORLength])
+	defer WipeBytes(secret)
 
 	seedSalt, err := randomBytes(SaltLength)
 	if err != nil {
@@ -140,15 +143,16 @@ func CreateKeySchedule(password string, keyfiles [][]byte, pim uint32, mnemonicS
 	if err != nil {
 		return nil, nil, err
 	}
+	defer WipeBytes(seedKey)
 
-	passSeedXOR := xorBytes(passKey, seedKey)
-
-	keys, err := deriveKeySchedule(passKey, seedKey, hkdfSalt)
+	wrappedSecret, err := sealVaultSecret(pwKey, secret, primarySecretLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+	seedWrappedSecret, err := sealVaultSecret(seedKey, secret, seedSecretLabel)
 	if err != nil {
-		WipeBytes(keyfileDigest)
 		return nil, nil, err
 	}
-	lockKeySchedule(keys)
 
 	var keyfileSalt []byte
 	var keyfileVerifier []byte
@@ -160,27 +164,283 @@ func CreateKeySchedule(password string, keyfiles [][]byte, pim uint32, mnemonicS
 		keyfileVerifier = ComputeAuthMAC(keyfileSalt, keyfileDigest)
 	}
 
-	meta := &KDFMetadata{
-		Version:          KDFMetadataVersion,
-		Params:           cloneParams(params),
-		PasswordVerifier: passwordVerifier,
-		PassSeedXOR:      passSeedXOR,
-		SeedSalt:         seedSalt,
-		HKDFSalt:         hkdfSalt,
-		KeyfileVerifier:  keyfileVerifier,
-		KeyfileSalt:      keyfileSalt,
-		PIM:              pim,
-	}
-
-	WipeBytes(derived)
-	WipeBytes(passKey)
-	WipeBytes(seedKey)
-	WipeBytes(keyfileDigest)
+	wrappedMasterKey, err := newWrappedMasterKey(secret, hkdfSalt)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := deriveKeySchedule(secret, hkdfSalt, wrappedMasterKey)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	meta := &KDFMetadata{
+		Version:           KDFMetadataVersion,
+		Params:            explicitKDFParams(params),
+		PasswordVerifier:  passwordVerifier,
+		WrappedSecret:     wrappedSecret,
+		SeedWrappedSecret: seedWrappedSecret,
+		WrappedMasterKey:  wrappedMasterKey,
+		SeedSalt:          seedSalt,
+		HKDFSalt:          hkdfSalt,
+		KeyfileVerifier:   keyfileVerifier,
+		KeyfileSalt:       keyfileSalt,
+		PIM:               pim,
+	}
 	return keys, meta, nil
 }
 
-func DeriveKeyScheduleFromPassword(password string, keyfiles [][]byte, pim uint32, meta *KDFMetadata) (*KeySchedule, error) {
+func DeriveKeyScheduleFromPassword(password []byte, keyfiles [][]byte, pim uint32, meta *KDFMetadata) (*KeySchedule, error) {
+	secret, _, err := unwrapVaultSecret(password, keyfiles, pim, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(secret)
+	return deriveKeySchedule(secret, meta.HKDFSalt, meta.WrappedMasterKey)
+}
+
+func RotateMasterKey(password []byte, keyfiles [][]byte, pim uint32, meta *KDFMetadata) (*KeySchedule, *KDFMetadata, error) {
+	secret, _, err := unwrapVaultSecret(password, keyfiles, pim, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeBytes(secret)
+
+	wrappedMasterKey, err := newWrappedMasterKey(secret, meta.HKDFSalt)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := deriveKeySchedule(secret, meta.HKDFSalt, wrappedMasterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := *meta
+	next.Params = cloneParams(meta.Params)
+	next.WrappedMasterKey = wrappedMasterKey
+	next.KeySlots = append([]KeySlot(nil), meta.KeySlots...)
+	return keys, &next, nil
+}
+
+func RekeyPassword(oldPassword []byte, oldKeyfiles [][]byte, oldPIM uint32, newPassword []byte, newKeyfiles [][]byte, newPIM uint32, meta *KDFMetadata) (*KDFMetadata, error) {
+	if len(newPassword) == 0 && len(newKeyfiles) == 0 {
+		return nil, errors.New("new password or keyfile required")
+	}
+
+	secret, slotIndex, err := unwrapVaultSecret(oldPassword, oldKeyfiles, oldPIM, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(secret)
+
+	if slotIndex < 0 {
+		return sealPasswordKeys(secret, newPassword, newKeyfiles, newPIM, meta.Params, meta)
+	}
+
+	current := meta.KeySlots[slotIndex]
+	slot, err := sealKeySlot(secret, current.ID, current.Label, newPassword, newKeyfiles, newPIM, meta.Params)
+	if err != nil {
+		return nil, err
+	}
+	slot.CreatedAt = current.CreatedAt
+
+	next := *meta
+	next.KeySlots = append([]KeySlot(nil), meta.KeySlots...)
+	next.KeySlots[slotIndex] = *slot
+	return &next, nil
+}
+
+func ResetPasswordWithSeed(mnemonicSeed []byte, newPassword []byte, newKeyfiles [][]byte, newPIM uint32, meta *KDFMetadata) (*KDFMetadata, error) {
+	if len(newPassword) == 0 && len(newKeyfiles) == 0 {
+		return nil, errors.New("new password or keyfile required")
+	}
+
+	secret, err := unwrapSeedSecret(mnemonicSeed, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(secret)
+
+	return sealPasswordKeys(secret, newPassword, newKeyfiles, newPIM, meta.Params, meta)
+}
+
+func RotateSeedKey(password []byte, keyfiles [][]byte, pim uint32, mnemonicSeed []byte, meta *KDFMetadata) (*KDFMetadata, error) {
+	if len(mnemonicSeed) == 0 {
+		return nil, errors.New("mnemonic seed cannot be empty")
+	}
+
+	secret, slotIndex, err := unwrapVaultSecret(password, keyfiles, pim, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(secret)
+	if len(meta.WrappedSecret) == 0 && slotIndex >= 0 {
+		return nil, errors.New("unlock with the primary password to rotate the recovery phrase of this vault")
+	}
+
+	seedSalt, err := randomBytes(SaltLength)
+	if err != nil {
+		return nil, err
+	}
+	seedKey, err := deriveSeedKey(mnemonicSeed, seedSalt)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(seedKey)
+	seedWrappedSecret, err := sealVaultSecret(seedKey, secret, seedSecretLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *meta
+	next.SeedSalt = seedSalt
+	next.SeedWrappedSecret = seedWrappedSecret
+	if len(meta.WrappedSecret) == 0 {
+		effectivePIM := meta.PIM
+		if pim != 0 {
+			effectivePIM = pim
+		}
+		return sealPasswordKeys(secret, password, keyfiles, effectivePIM, meta.Params, &next)
+	}
+	next.Params = cloneParams(meta.Params)
+	next.KeySlots = append([]KeySlot(nil), meta.KeySlots...)
+	return &next, nil
+}
+
+func UpgradeKDF(key *VaultKey, password []byte, keyfiles [][]byte, pim uint32, meta *KDFMetadata) (*KDFMetadata, bool, error) {
+	if key == nil {
+		return nil, false, errors.New("vault key cannot be nil")
+	}
+	if err := validateMetadata(meta); err != nil {
+		return nil, false, err
+	}
+	if key.slot >= len(meta.KeySlots) {
+		return nil, false, errors.New("key slot not found")
+	}
+
+	params, storedPIM := meta.Params, meta.PIM
+	if key.slot >= 0 {
+		params, storedPIM = meta.KeySlots[key.slot].Params, meta.KeySlots[key.slot].PIM
+	}
+	if pim == 0 {
+		pim = storedPIM
+	}
+
+	kdf, err := LookupKDF(params.KDF)
+	if err != nil {
+		return nil, false, err
+	}
+	upgraded, changed := kdf.Upgrade(params)
+
+	if key.slot < 0 {
+		legacy := meta.Version < KDFMetadataVersion || len(meta.WrappedSecret) == 0 || len(meta.Params.KDF) == 0
+		if !changed && !legacy {
+			return meta, false, nil
+		}
+		next, err := sealPasswordKeys(key.secret, password, keyfiles, pim, upgraded, meta)
+		if err != nil {
+			return nil, false, err
+		}
+		return next, true, nil
+	}
+	if !changed {
+		return meta, false, nil
+	}
+
+	current := meta.KeySlots[key.slot]
+	slot, err := sealKeySlot(key.secret, current.ID, current.Label, password, keyfiles, pim, upgraded)
+	if err != nil {
+		return nil, false, err
+	}
+	slot.CreatedAt = current.CreatedAt
+
+	next := *meta
+	next.Params = cloneParams(meta.Params)
+	next.KeySlots = append([]KeySlot(nil), meta.KeySlots...)
+	next.KeySlots[key.slot] = *slot
+	return &next, true, nil
+}
+
+func sealPasswordKeys(secret []byte, password []byte, keyfiles [][]byte, pim uint32, baseParams *KDFParams, meta *KDFMetadata) (*KDFMetadata, error) {
+	if len(secret) != vaultSecretLength {
+		return nil, errors.New("invalid vault secret length")
+	}
+	salt, err := randomBytes(SaltLength)
+	if err != nil {
+		return nil, err
+	}
+	params := explicitKDFParams(baseParams)
+	params.Salt = salt
+
+	combinedPassword, keyfileDigest, err := combinePasswordAndKeyfiles(password, keyfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(combinedPassword)
+	defer WipeBytes(keyfileDigest)
+
+	passwordVerifier, pwKey, err := derivePasswordKey(combinedPassword, params, pim)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(pwKey)
+
+	wrappedSecret, err := sealVaultSecret(pwKey, secret, primarySecretLabel)
+	if err != nil {
+		return nil, err
+	}
+	seedWrappedSecret := append([]byte(nil), meta.SeedWrappedSecret...)
+	if len(seedWrappedSecret) == 0 {
+		seedWrappedSecret, err = sealVaultSecret(secret[passSeedXORLength:], secret, seedSecretLabel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keyfileSalt []byte
+	var keyfileVerifier []byte
+	if keyfileDigest != nil {
+		keyfileSalt, err = randomBytes(SaltLength)
+		if err != nil {
+			return nil, err
+		}
+		keyfileVerifier = ComputeAuthMAC(keyfileSalt, keyfileDigest)
+	}
+
+	next := *meta
+	next.Version = KDFMetadataVersion
+	next.Params = params
+	next.PasswordVerifier = passwordVerifier
+	next.WrappedSecret = wrappedSecret
+	next.SeedWrappedSecret = seedWrappedSecret
+	next.PassSeedXOR = nil
+	next.PassKeyXOR = nil
+	next.SeedSalt = append([]byte(nil), meta.SeedSalt...)
+	next.HKDFSalt = append([]byte(nil), meta.HKDFSalt...)
+	next.KeyfileVerifier = keyfileVerifier
+	next.KeyfileSalt = keyfileSalt
+	next.PIM = pim
+	next.KeySlots = append([]KeySlot(nil), meta.KeySlots...)
+	return &next, nil
+}
+
+func unwrapVaultSecret(password []byte, keyfiles [][]byte, pim uint32, meta *KDFMetadata) ([]byte, int, error) {
+	if err := validateMetadata(meta); err != nil {
+		return nil, -1, err
+	}
+	secret, err := unwrapPasswordSecret(password, keyfiles, pim, meta)
+	if err == nil {
+		return secret, -1, nil
+	}
+	for i := range meta.KeySlots {
+		slotSecret, slotErr := openKeySlot(&meta.KeySlots[i], password, keyfiles, pim)
+		if slotErr == nil {
+			return slotSecret, i, nil
+		}
+	}
+	return nil, -1, err
+}
+
+func unwrapPasswordSecret(password []byte, keyfiles [][]byte, pim uint32, meta *KDFMetadata) ([]byte, error) {
 	if err := validateMetadata(meta); err != nil {
 		return nil, err
 	}
@@ -193,80 +453,84 @@ func DeriveKeyScheduleFromPassword(password string, keyfiles [][]byte, pim uint3
 		return nil, err
 	}
 	defer WipeBytes(combinedPassword)
+	defer WipeBytes(keyfileDigest)
 
 	effectivePIM := meta.PIM
 	if pim != 0 {
 		effectivePIM = pim
 	}
 
-	derivedParams := cloneParams(meta.Params)
-	if effectivePIM > 0 {
-		next, err := applyPIMIncrement(derivedParams.Time, effectivePIM)
-		if err != nil {
-			return nil, err
-		}
-		derivedParams.Time = next
-	}
-
-	derived := argon2.IDKey(combinedPassword, derivedParams.Salt, derivedParams.Time, derivedParams.Memory, derivedParams.Threads, derivedParams.KeyLength)
-	if len(derived) < passwordVerifierLength+passSeedXORLength {
-		return nil, errors.New("derived key material is too short")
+	verifier, pwKey, err := derivePasswordKey(combinedPassword, meta.Params, effectivePIM)
+	if err != nil {
+		return nil, err
 	}
+	defer WipeBytes(pwKey)
 
-	if subtle.ConstantTimeCompare(derived[:passwordVerifierLength], meta.PasswordVerifier) != 1 {
-		WipeBytes(derived)
-		WipeBytes(keyfileDigest)
+	if subtle.ConstantTimeCompare(verifier, meta.PasswordVerifier) != 1 {
 		return nil, errors.New("invalid password")
 	}
 
-	passKey := make([]byte, passSeedXORLength)
-	copy(passKey, derived[passwordVerifierLength:passwordVerifierLength+passSeedXORLength])
-	seedKey := xorBytes(passKey, meta.PassSeedXOR)
-
 	if len(meta.KeyfileVerifier) > 0 {
 		if keyfileDigest == nil {
-			WipeBytes(derived)
-			WipeBytes(passKey)
-			WipeBytes(seedKey)
 			return nil, errors.New("missing keyfiles")
 		}
 		expected := ComputeAuthMAC(meta.KeyfileSalt, keyfileDigest)
 		if subtle.ConstantTimeCompare(expected, meta.KeyfileVerifier) != 1 {
 			WipeBytes(expected)
-			WipeBytes(derived)
-			WipeBytes(passKey)
-			WipeBytes(seedKey)
-			WipeBytes(keyfileDigest)
 			return nil, errors.New("invalid keyfiles")
 		}
 		WipeBytes(expected)
 	} else if keyfileDigest != nil {
-		WipeBytes(derived)
-		WipeBytes(passKey)
-		WipeBytes(seedKey)
-		WipeBytes(keyfileDigest)
 		return nil, errors.New("unexpected keyfiles")
 	}
 
-	keys, err := deriveKeySchedule(passKey, seedKey, meta.HKDFSalt)
-	if err != nil {
-		WipeBytes(derived)
+	if len(meta.WrappedSecret) > 0 {
+		secret, err := openVaultSecret(pwKey, meta.WrappedSecret, primarySecretLabel)
+		if err != nil {
+			return nil, errors.New("invalid password")
+		}
+		return secret, nil
+	}
+
+	seedKey := xorBytes(pwKey, meta.PassSeedXOR)
+	passKey := append([]byte(nil), pwKey...)
+	if len(meta.PassKeyXOR) > 0 {
 		WipeBytes(passKey)
-		WipeBytes(seedKey)
-		WipeBytes(keyfileDigest)
-		return nil, err
+		passKey = xorBytes(pwKey, meta.PassKeyXOR)
+	}
+	return legacyVaultSecret(passKey, seedKey), nil
+}
+
+func derivePasswordKey(combinedPassword []byte, params *KDFParams, pim uint32) ([]byte, []byte, error) {
+	kdf, err := LookupKDF(params.KDF)
+	if err != nil {
+		return nil, nil, err
 	}
-	lockKeySchedule(keys)
 
-	WipeBytes(derived)
-	WipeBytes(passKey)
-	WipeBytes(seedKey)
-	WipeBytes(keyfileDigest)
+	derived, err := kdf.Derive(combinedPassword, params, pim)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeBytes(derived)
+	if len(derived) < passwordVerifierLength+passSeedXORLength {
+		return nil, nil, errors.New("derived key material is too short")
+	}
 
-	return keys, nil
+	verifier := append([]byte(nil), derived[:passwordVerifierLength]...)
+	pwKey := append([]byte(nil), derived[passwordVerifierLength:passwordVerifierLength+passSeedXORLength]...)
+	return verifier, pwKey, nil
 }
 
 func DeriveKeyScheduleFromSeed(mnemonicSeed []byte, meta *KDFMetadata) (*KeySchedule, error) {
+	secret, err := unwrapSeedSecret(mnemonicSeed, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(secret)
+	return deriveKeySchedule(secret, meta.HKDFSalt, meta.WrappedMasterKey)
+}
+
+func unwrapSeedSecret(mnemonicSeed []byte, meta *KDFMetadata) ([]byte, error) {
 	if err := validateMetadata(meta); err != nil {
 		return nil, err
 	}
@@ -279,18 +543,54 @@ func DeriveKeyScheduleFromSeed(mnemonicSeed []byte, meta *KDFMetadata) (*KeySche
 		return nil, err
 	}
 
+	if len(meta.SeedWrappedSecret) > 0 {
+		defer WipeBytes(seedKey)
+		secret, err := openVaultSecret(seedKey, meta.SeedWrappedSecret, seedSecretLabel)
+		if err != nil {
+			return nil, errors.New("recovery phrase does not match this vault")
+		}
+		return secret, nil
+	}
+
 	passKey := xorBytes(seedKey, meta.PassSeedXOR)
-	keys, err := deriveKeySchedule(passKey, seedKey, meta.HKDFSalt)
+	if len(meta.PassKeyXOR) > 0 {
+		pwKey := passKey
+		passKey = xorBytes(pwKey, meta.PassKeyXOR)
+		WipeBytes(pwKey)
+	}
+	return legacyVaultSecret(passKey, seedKey), nil
+}
+
+func legacyVaultSecret(passKey, seedKey []byte) []byte {
+	defer WipeBytes(passKey)
+	defer WipeBytes(seedKey)
+	secret := make([]byte, 0, vaultSecretLength)
+	secret = append(secret, passKey...)
+	return append(secret, seedKey...)
+}
+
+func sealVaultSecret(kek, secret []byte, label string) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(kek)
 	if err != nil {
-		WipeBytes(seedKey)
-		WipeBytes(passKey)
 		return nil, err
 	}
+	nonce, err := randomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, secret, []byte(label)), nil
+}
 
-	WipeBytes(seedKey)
-	WipeBytes(passKey)
-	lockKeySchedule(keys)
-	return keys, nil
+func openVaultSecret(kek, wrapped []byte, label string) ([]byte, error) {
+	if len(wrapped) != wrappedVaultKeyLength {
+		return nil, errors.New("invalid wrapped key length")
+	}
+	aead, err := chacha20poly1305.NewX(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(label))
 }
 
 func ComputeAuthMAC(authKey []byte, data []byte) []byte {
@@ -320,46 +620,107 @@ func deriveSeedKey(seed []byte, salt []byte) ([]byte, error) {
 	return key, nil
 }
 
-func deriveKeySchedule(passKey, seedKey, hkdfSalt []byte) (*KeySchedule, error) {
-	if len(passKey) != passSeedXORLength || len(seedKey) != passSeedXORLength {
+func deriveKeySchedule(secret, hkdfSalt, wrappedMasterKey []byte) (*KeySchedule, error) {
+	if len(secret) != vaultSecretLength {
 		return nil, errors.New("invalid key material length")
 	}
 	if len(hkdfSalt) == 0 {
 		return nil, errors.New("HKDF salt cannot be empty")
 	}
 
-	ikm := make([]byte, 0, len(passKey)+len(seedKey))
-	ikm = append(ikm, passKey...)
-	ikm = append(ikm, seedKey...)
-
-	masterKey, err := deriveHKDFKey(ikm, hkdfSalt, kdfInfoLabel+"/master")
+	var masterKey []byte
+	var err error
+	if len(wrappedMasterKey) > 0 {
+		masterKey, err = unwrapMasterKey(secret, hkdfSalt, wrappedMasterKey)
+	} else {
+		masterKey, err = deriveHKDFKey(secret, hkdfSalt, kdfInfoLabel+"/master")
+	}
 	if err != nil {
-		WipeBytes(ikm)
 		return nil, err
 	}
-	authKey, err := deriveHKDFKey(ikm, hkdfSalt, kdfInfoLabel+"/auth")
+	authKey, err := deriveHKDFKey(secret, hkdfSalt, kdfInfoLabel+"/auth")
 	if err != nil {
-		WipeBytes(ikm)
 		WipeBytes(masterKey)
 		return nil, err
 	}
-	metadataKey, err := deriveHKDFKey(ikm, hkdfSalt, kdfInfoLabel+"/metadata")
+	metadataKey, err := deriveHKDFKey(secret, hkdfSalt, kdfInfoLabel+"/metadata")
 	if err != nil {
-		WipeBytes(ikm)
 		WipeBytes(masterKey)
 		WipeBytes(authKey)
 		return nil, err
 	}
 
-	WipeBytes(ikm)
+	return guardKeySchedule(masterKey, authKey, metadataKey)
+}
 
+func newWrappedMasterKey(secret, hkdfSalt []byte) ([]byte, error) {
+	masterKey, err := NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(masterKey)
+	kek, err := deriveHKDFKey(secret, hkdfSalt, masterKeyWrapLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(kek)
+	return WrapDataKey(kek, masterKey, []byte(masterKeyWrapLabel))
+}
+
+func unwrapMasterKey(secret, hkdfSalt, wrappedMasterKey []byte) ([]byte, error) {
+	kek, err := deriveHKDFKey(secret, hkdfSalt, masterKeyWrapLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(kek)
+	masterKey, err := UnwrapDataKey(kek, wrappedMasterKey, []byte(masterKeyWrapLabel))
+	if err != nil {
+		return nil, errors.New("master key unwrap failed")
+	}
+	return masterKey, nil
+}
+
+func guardKeySchedule(masterKey, authKey, metadataKey []byte) (*KeySchedule, error) {
+	defer WipeBytes(masterKey)
+	defer WipeBytes(authKey)
+	defer WipeBytes(metadataKey)
+	enclave, err := NewEnclave(len(masterKey) + len(authKey) + len(metadataKey))
+	if err != nil {
+		return nil, err
+	}
+	data, err := enclave.Open()
+	if err != nil {
+		enclave.Destroy()
+		return nil, err
+	}
+	authOffset := len(masterKey)
+	metadataOffset := authOffset + len(authKey)
+	copy(data, masterKey)
+	copy(data[authOffset:], authKey)
+	copy(data[metadataOffset:], metadataKey)
 	return &KeySchedule{
-		MasterKey:   masterKey,
-		AuthKey:     authKey,
-		MetadataKey: metadataKey,
+		MasterKey:   data[:authOffset:authOffset],
+		AuthKey:     data[authOffset:metadataOffset:metadataOffset],
+		MetadataKey: data[metadataOffset:],
+		enclave:     enclave,
 	}, nil
 }
 
+func NewMetadataSaveCipher(metadataKey, saveSalt []byte) (*Cipher, error) {
+	if len(metadataKey) != passSeedXORLength {
+		return nil, errors.New("invalid metadata key length")
+	}
+	if len(saveSalt) != SaltLength {
+		return nil, errors.New("invalid metadata salt length")
+	}
+	key, err := deriveHKDFKey(metadataKey, saveSalt, kdfInfoLabel+"/metadata-save")
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(key)
+	return NewCipher(XChaCha20Poly1305, key)
+}
+
 func deriveHKDFKey(ikm, salt []byte, info string) ([]byte, error) {
 	reader := hkdf.New(sha256.New, ikm, salt, []byte(info))
 	key := make([]byte, passSeedXORLength)
@@ -387,19 +748,23 @@ func validateParams(params *KDFParams) error {
 	if params.Salt == nil || len(params.Salt) != SaltLength {
 		return errors.New("invalid salt length")
 	}
-	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
-		return errors.New("invalid KDF parameters")
-	}
 	if params.KeyLength < passwordVerifierLength+passSeedXORLength {
 		return errors.New("key length must be at least 64 bytes")
 	}
-	return nil
+	kdf, err := LookupKDF(params.KDF)
+	if err != nil {
+		return err
+	}
+	return kdf.Validate(params)
 }
 
 func validateMetadata(meta *KDFMetadata) error {
 	if meta == nil {
 		return errors.New("KDF metadata cannot be nil")
 	}
+	if meta.Version < legacyKDFVersion || meta.Version > KDFMetadataVersion {
+		return fmt.Errorf("unsupported KDF metadata version %d", meta.Version)
+	}
 	if meta.Params == nil {
 		return errors.New("KDF params cannot be nil")
 	}
@@ -409,8 +774,17 @@ func validateMetadata(meta *KDFMetadata) error {
 	if len(meta.PasswordVerifier) != passwordVerifierLength {
 		return errors.New("invalid password verifier length")
 	}
-	if len(meta.PassSeedXOR) != passSeedXORLength {
-		return errors.New("invalid pass-seed xor length")
+	if len(meta.WrappedSecret) > 0 || len(meta.SeedWrappedSecret) > 0 || meta.Version == KDFMetadataVersion {
+		if len(meta.WrappedSecret) != wrappedVaultKeyLength || len(meta.SeedWrappedSecret) != wrappedVaultKeyLength {
+			return errors.New("invalid wrapped vault secret length")
+		}
+	} else {
+		if len(meta.PassSeedXOR) != passSeedXORLength {
+			return errors.New("invalid pass-seed xor length")
+		}
+		if len(meta.PassKeyXOR) > 0 && len(meta.PassKeyXOR) != passSeedXORLength {
+			return errors.New("invalid pass-key xor length")
+		}
 	}
 	if len(meta.SeedSalt) != SaltLength {
 		return errors.New("invalid seed salt length")
@@ -418,20 +792,75 @@ func validateMetadata(meta *KDFMetadata) error {
 	if len(meta.HKDFSalt) != SaltLength {
 		return errors.New("invalid hkdf salt length")
 	}
+	if len(meta.WrappedMasterKey) > 0 && len(meta.WrappedMasterKey) != wrappedDataKeyLen {
+		return errors.New("invalid wrapped master key length")
+	}
 	if len(meta.KeyfileVerifier) > 0 && len(meta.KeyfileSalt) != SaltLength {
 		return errors.New("invalid keyfile salt length")
 	}
+	if len(meta.KeySlots) > MaxKeySlots {
+		return errors.New("too many key slots")
+	}
+	for i := range meta.KeySlots {
+		if err := validateKeySlot(&meta.KeySlots[i]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (ks *KeySchedule) Open() error {
+	if ks == nil || ks.enclave == nil {
+		return errors.New("key schedule has been wiped")
+	}
+	_, err := ks.enclave.Open()
+	return err
+}
+
+func (ks *KeySchedule) Seal() {
+	if ks == nil || ks.enclave == nil {
+		return
+	}
+	ks.enclave.Seal()
+}
+
+func (ks *KeySchedule) Clone() (*KeySchedule, error) {
+	if err := ks.Open(); err != nil {
+		return nil, err
+	}
+	defer ks.Seal()
+	return guardKeySchedule(
+		append([]byte(nil), ks.MasterKey...),
+		append([]byte(nil), ks.AuthKey...),
+		append([]byte(nil), ks.MetadataKey...),
+	)
+}
+
+func (ks *KeySchedule) Locked() bool {
+	return ks != nil && ks.enclave != nil && ks.enclave.Locked()
+}
+
+func (ks *KeySchedule) Guarded() bool {
+	return ks != nil && ks.enclave != nil && ks.enclave.Guarded()
+}
+
+func (ks *KeySchedule) Sealed() bool {
+	return ks != nil && ks.enclave != nil && ks.enclave.Sealed()
+}
+
 func (ks *KeySchedule) Wipe() {
 	if ks == nil {
 		return
 	}
-	unlockKeySchedule(ks)
-	WipeBytes(ks.MasterKey)
-	WipeBytes(ks.AuthKey)
-	WipeBytes(ks.MetadataKey)
+	if ks.enclave != nil {
+		ks.enclave.Destroy()
+		ks.enclave = nil
+	} else {
+		WipeBytes(ks.MasterKey)
+		WipeBytes(ks.AuthKey)
+		WipeBytes(ks.MetadataKey)
+	}
+	ks.MasterKey, ks.AuthKey, ks.MetadataKey = nil, nil, nil
 }
 
 func randomBytes(length int) ([]byte, error) {
@@ -453,6 +882,14 @@ func cloneParams(params *KDFParams) *KDFParams {
 	return &c
 }
 
+func explicitKDFParams(params *KDFParams) *KDFParams {
+	c := cloneParams(params)
+	if c != nil && len(c.KDF) == 0 {
+		c.KDF = DefaultKDF
+	}
+	return c
+}
+
 func xorBytes(a, b []byte) []byte {
 	if len(a) != len(b) {
 		return nil
@@ -464,28 +901,9 @@ func xorBytes(a, b []byte) []byte {
 	return out
 }
 
-func lockKeySchedule(keys *KeySchedule) {
-	if keys == nil {
-		return
-	}
-	LockBytes(keys.MasterKey)
-	LockBytes(keys.AuthKey)
-	LockBytes(keys.MetadataKey)
-}
-
-func unlockKeySchedule(keys *KeySchedule) {
-	if keys == nil {
-		return
-	}
-	UnlockBytes(keys.MasterKey)
-	UnlockBytes(keys.AuthKey)
-	UnlockBytes(keys.MetadataKey)
-}
-
-func combinePasswordAndKeyfiles(password string, keyfiles [][]byte) ([]byte, []byte, error) {
-	pwd := []byte(password)
-	defer WipeBytes(pwd)
-	combined := append([]byte(nil), pwd...)
+func combinePasswordAndKeyfiles(password []byte, keyfiles [][]byte) ([]byte, []byte, error) {
+	combined := make([]byte, 0, len(password)+sha256.Size)
+	combined = append(combined, password...)
 	if len(keyfiles) == 0 {
 		return combined, nil, nil
 	}
@@ -1,6 +1,9 @@
 package crypto
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestApplyPIMIncrementSuccess(t *testing.T) {
 	next, err := applyPIMIncrement(3, 5)
@@ -25,3 +28,322 @@ func TestApplyPIMIncrementTooLarge(t *testing.T) {
 		t.Fatal("expected size error")
 	}
 }
+
+func testKDFParams(t *testing.T) *KDFParams {
+	t.Helper()
+	salt, err := GenerateSalt()
+	if err != nil {
+		t.Fatalf("salt: %v", err)
+	}
+	params := NewKDFParams(salt)
+	params.Time = 1
+	params.Memory = 8 * 1024
+	return params
+}
+
+func TestRekeyPasswordKeepsKeySchedule(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, 64)
+	keys, meta, err := CreateKeySchedule([]byte("old-password"), nil, 0, seed, testKDFParams(t))
+	if err != nil {
+		t.Fatalf("create key schedule: %v", err)
+	}
+	defer keys.Wipe()
+
+	keyfile := []byte("keyfile contents")
+	rekeyed, err := RekeyPassword([]byte("old-password"), nil, 0, []byte("new-password"), [][]byte{keyfile}, 2, meta)
+	if err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	if bytes.Equal(rekeyed.Params.Salt, meta.Params.Salt) {
+		t.Fatal("expected fresh password salt")
+	}
+
+	if _, err := DeriveKeyScheduleFromPassword([]byte("old-password"), nil, 0, rekeyed); err == nil {
+		t.Fatal("expected old password to be rejected")
+	}
+	if _, err := DeriveKeyScheduleFromPassword([]byte("new-password"), nil, 2, rekeyed); err == nil {
+		t.Fatal("expected missing keyfile to be rejected")
+	}
+
+	fromPassword, err := DeriveKeyScheduleFromPassword([]byte("new-password"), [][]byte{keyfile}, 2, rekeyed)
+	if err != nil {
+		t.Fatalf("derive from new password: %v", err)
+	}
+	defer fromPassword.Wipe()
+	fromSeed, err := DeriveKeyScheduleFromSeed(seed, rekeyed)
+	if err != nil {
+		t.Fatalf("derive from seed: %v", err)
+	}
+	defer fromSeed.Wipe()
+
+	for _, ks := range []*KeySchedule{fromPassword, fromSeed} {
+		if !bytes.Equal(ks.MasterKey, keys.MasterKey) || !bytes.Equal(ks.AuthKey, keys.AuthKey) || !bytes.Equal(ks.MetadataKey, keys.MetadataKey) {
+			t.Fatal("key schedule changed after rekey")
+		}
+	}
+}
+
+func TestRegisteredKDFsDeriveKeySchedule(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x24}, 64)
+	for _, id := range []string{KDFArgon2id, KDFScrypt, KDFArgon2idPIMMemory} {
+		salt, err := GenerateSalt()
+		if err != nil {
+			t.Fatalf("salt: %v", err)
+		}
+		params, err := NewKDFParamsFor(id, salt)
+		if err != nil {
+			t.Fatalf("%s params: %v", id, err)
+		}
+		if id == KDFScrypt {
+			params.LogN = 10
+		} else {
+			params.Time, params.Memory = 1, 8*1024
+		}
+
+		keys, meta, err := CreateKeySchedule([]byte("password"), nil, 3, seed, params)
+		if err != nil {
+			t.Fatalf("%s create: %v", id, err)
+		}
+		if meta.Version != KDFMetadataVersion || meta.Params.KDF != id {
+			t.Fatalf("%s: metadata records version %d kdf %q", id, meta.Version, meta.Params.KDF)
+		}
+
+		derived, err := DeriveKeyScheduleFromPassword([]byte("password"), nil, 0, meta)
+		if err != nil {
+			t.Fatalf("%s derive: %v", id, err)
+		}
+		if !bytes.Equal(derived.MasterKey, keys.MasterKey) {
+			t.Fatalf("%s: key schedule mismatch", id)
+		}
+		if _, err := DeriveKeyScheduleFromPassword([]byte("password"), nil, 4, meta); err == nil {
+			t.Fatalf("%s: expected wrong PIM to be rejected", id)
+		}
+		keys.Wipe()
+		derived.Wipe()
+	}
+}
+
+func TestLegacyKDFMetadataUpgrades(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x24}, 64)
+	meta := legacyKDFMetadata(t, []byte("password"), seed)
+	meta.Version = legacyKDFVersion
+	meta.Params.KDF = ""
+	keys, err := DeriveKeyScheduleFromPassword([]byte("password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("derive legacy keys: %v", err)
+	}
+	defer keys.Wipe()
+
+	vaultKey, err := UnwrapVaultKey([]byte("password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("unwrap vault key: %v", err)
+	}
+	upgraded, changed, err := UpgradeKDF(vaultKey, []byte("password"), nil, 0, meta)
+	vaultKey.Wipe()
+	if err != nil || !changed {
+		t.Fatalf("upgrade: changed=%v err=%v", changed, err)
+	}
+	if upgraded.Version != KDFMetadataVersion || upgraded.Params.KDF != KDFArgon2id || len(upgraded.WrappedSecret) == 0 {
+		t.Fatalf("unexpected upgraded metadata: version %d kdf %q", upgraded.Version, upgraded.Params.KDF)
+	}
+	if upgraded.Params.Memory < Argon2DefaultMemory || upgraded.Params.Time < Argon2DefaultTime {
+		t.Fatalf("expected default cost, got time=%d memory=%d", upgraded.Params.Time, upgraded.Params.Memory)
+	}
+
+	derived, err := DeriveKeyScheduleFromPassword([]byte("password"), nil, 0, upgraded)
+	if err != nil {
+		t.Fatalf("derive after upgrade: %v", err)
+	}
+	defer derived.Wipe()
+	if !bytes.Equal(derived.MasterKey, keys.MasterKey) {
+		t.Fatal("key schedule changed after KDF upgrade")
+	}
+}
+
+func TestUpgradeKDFOnlyResealsTheUnlockingSlot(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x25}, 64)
+	salt, _ := GenerateSalt()
+	keys, meta, err := CreateKeySchedule([]byte("password"), nil, 0, seed, NewKDFParams(salt))
+	if err != nil {
+		t.Fatalf("create key schedule: %v", err)
+	}
+	defer keys.Wipe()
+
+	primaryKey, err := UnwrapVaultKey([]byte("password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("unwrap primary: %v", err)
+	}
+	defer primaryKey.Wipe()
+	slot, err := NewKeySlot(primaryKey, "weak", []byte("slot-password"), nil, 0, testKDFParams(t))
+	if err != nil {
+		t.Fatalf("new key slot: %v", err)
+	}
+	meta.KeySlots = []KeySlot{*slot}
+
+	if next, changed, err := UpgradeKDF(primaryKey, []byte("password"), nil, 0, meta); err != nil || changed || next != meta {
+		t.Fatalf("expected a current primary to be left alone, changed=%v err=%v", changed, err)
+	}
+
+	slotKey, err := UnwrapVaultKey([]byte("slot-password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("unwrap slot: %v", err)
+	}
+	defer slotKey.Wipe()
+	upgraded, changed, err := UpgradeKDF(slotKey, []byte("slot-password"), nil, 0, meta)
+	if err != nil || !changed {
+		t.Fatalf("upgrade slot: changed=%v err=%v", changed, err)
+	}
+	if !bytes.Equal(upgraded.PasswordVerifier, meta.PasswordVerifier) || !bytes.Equal(upgraded.WrappedSecret, meta.WrappedSecret) {
+		t.Fatal("expected the primary to stay untouched")
+	}
+	if upgraded.KeySlots[0].Params.Memory < Argon2DefaultMemory {
+		t.Fatalf("expected slot cost to be raised, got memory=%d", upgraded.KeySlots[0].Params.Memory)
+	}
+
+	derived, err := DeriveKeyScheduleFromPassword([]byte("slot-password"), nil, 0, upgraded)
+	if err != nil {
+		t.Fatalf("derive through upgraded slot: %v", err)
+	}
+	defer derived.Wipe()
+	if !bytes.Equal(derived.MasterKey, keys.MasterKey) {
+		t.Fatal("key schedule changed after slot upgrade")
+	}
+}
+
+func TestMetadataSaveCipherUsesPerSaveKeys(t *testing.T) {
+	metadataKey := bytes.Repeat([]byte{0x07}, 32)
+	firstSalt := bytes.Repeat([]byte{0x01}, SaltLength)
+	secondSalt := bytes.Repeat([]byte{0x02}, SaltLength)
+
+	first, err := NewMetadataSaveCipher(metadataKey, firstSalt)
+	if err != nil {
+		t.Fatalf("first cipher: %v", err)
+	}
+	second, err := NewMetadataSaveCipher(metadataKey, secondSalt)
+	if err != nil {
+		t.Fatalf("second cipher: %v", err)
+	}
+	if first.Type != XChaCha20Poly1305 {
+		t.Fatalf("expected XChaCha20-Poly1305, got %d", first.Type)
+	}
+
+	sealed, err := first.Encrypt([]byte("index"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if _, err := second.Decrypt(sealed); err == nil {
+		t.Fatal("expected a different save salt to yield a different key")
+	}
+	if _, err := NewMetadataSaveCipher(metadataKey, nil); err == nil {
+		t.Fatal("expected missing save salt to be rejected")
+	}
+}
+
+func TestWrappedDataKeyIsBoundToMasterKeyAndContext(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{0x11}, 32)
+	dataKey, err := NewDataKey()
+	if err != nil {
+		t.Fatalf("new data key: %v", err)
+	}
+	wrapped, err := WrapDataKey(masterKey, dataKey, []byte("entry-a"))
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+
+	unwrapped, err := UnwrapDataKey(masterKey, wrapped, []byte("entry-a"))
+	if err != nil {
+		t.Fatalf("unwrap: %v", err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Fatal("unwrapped data key differs")
+	}
+	if _, err := UnwrapDataKey(masterKey, wrapped, []byte("entry-b")); err == nil {
+		t.Fatal("expected other associated data to be rejected")
+	}
+	if _, err := UnwrapDataKey(bytes.Repeat([]byte{0x22}, 32), wrapped, []byte("entry-a")); err == nil {
+		t.Fatal("expected other master key to be rejected")
+	}
+}
+
+func legacyKDFMetadata(t *testing.T, password, seed []byte) *KDFMetadata {
+	t.Helper()
+	params := testKDFParams(t)
+	verifier, pwKey, err := derivePasswordKey(append([]byte(nil), password...), params, 0)
+	if err != nil {
+		t.Fatalf("derive password key: %v", err)
+	}
+	seedSalt, _ := GenerateSalt()
+	hkdfSalt, _ := GenerateSalt()
+	seedKey, err := deriveSeedKey(seed, seedSalt)
+	if err != nil {
+		t.Fatalf("derive seed key: %v", err)
+	}
+	passKey := bytes.Repeat([]byte{0x5a}, passSeedXORLength)
+	return &KDFMetadata{
+		Version:          2,
+		Params:           params,
+		PasswordVerifier: verifier,
+		PassSeedXOR:      xorBytes(pwKey, seedKey),
+		PassKeyXOR:       xorBytes(pwKey, passKey),
+		SeedSalt:         seedSalt,
+		HKDFSalt:         hkdfSalt,
+	}
+}
+
+func TestLegacyVaultSecretSurvivesPrimaryConversion(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x33}, 64)
+	meta := legacyKDFMetadata(t, []byte("password"), seed)
+	keys, err := DeriveKeyScheduleFromPassword([]byte("password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("derive legacy keys: %v", err)
+	}
+	defer keys.Wipe()
+
+	vaultKey, err := UnwrapVaultKey([]byte("password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("unwrap vault key: %v", err)
+	}
+	slot, err := NewKeySlot(vaultKey, "colleague", []byte("slot-password"), nil, 0, meta.Params)
+	if err != nil {
+		t.Fatalf("new key slot: %v", err)
+	}
+	recoveryFile, content, err := NewRecoveryFileSlot(vaultKey)
+	if err != nil {
+		t.Fatalf("new recovery file: %v", err)
+	}
+	vaultKey.Wipe()
+	meta.KeySlots = []KeySlot{*slot}
+	meta.RecoveryFile = recoveryFile
+
+	converted, err := RekeyPassword([]byte("password"), nil, 0, []byte("new-password"), nil, 0, meta)
+	if err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	if converted.Version != KDFMetadataVersion || len(converted.WrappedSecret) == 0 || len(converted.SeedWrappedSecret) == 0 {
+		t.Fatalf("expected wrapped vault secret, got version %d", converted.Version)
+	}
+	if converted.PassSeedXOR != nil || converted.PassKeyXOR != nil {
+		t.Fatal("expected legacy xor fields to be dropped")
+	}
+
+	derived := []func() (*KeySchedule, error){
+		func() (*KeySchedule, error) {
+			return DeriveKeyScheduleFromPassword([]byte("new-password"), nil, 0, converted)
+		},
+		func() (*KeySchedule, error) {
+			return DeriveKeyScheduleFromPassword([]byte("slot-password"), nil, 0, converted)
+		},
+		func() (*KeySchedule, error) { return DeriveKeyScheduleFromSeed(seed, converted) },
+		func() (*KeySchedule, error) { return DeriveKeyScheduleFromRecoveryFile(content, converted) },
+	}
+	for i, derive := range derived {
+		ks, err := derive()
+		if err != nil {
+			t.Fatalf("credential %d: %v", i, err)
+		}
+		if !bytes.Equal(ks.MasterKey, keys.MasterKey) || !bytes.Equal(ks.AuthKey, keys.AuthKey) {
+			t.Fatalf("credential %d: key schedule changed after conversion", i)
+		}
+		ks.Wipe()
+	}
+}
@@ -0,0 +1,217 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KDFArgon2id          = "argon2id"
+	KDFScrypt            = "scrypt"
+	KDFArgon2idPIMMemory = "argon2id-pim-memory"
+	DefaultKDF           = KDFArgon2id
+
+	ScryptDefaultLogN = 17
+	ScryptDefaultR    = 8
+	ScryptDefaultP    = 1
+	scryptMaxLogN     = 30
+
+	pimMemoryStep      = 1024
+	maxArgon2Memory    = 4 * 1024 * 1024
+	argon2DefaultCost  = uint64(Argon2DefaultMemory) * Argon2DefaultTime
+	scryptMaxBlockCost = 1 << 30
+)
+
+type KDF interface {
+	ID() string
+	DefaultParams(salt []byte) *KDFParams
+	Validate(params *KDFParams) error
+	Derive(password []byte, params *KDFParams, pim uint32) ([]byte, error)
+	Upgrade(params *KDFParams) (*KDFParams, bool)
+}
+
+var (
+	kdfRegistryMu sync.RWMutex
+	kdfRegistry   = map[string]KDF{}
+)
+
+func init() {
+	RegisterKDF(argon2idKDF{})
+	RegisterKDF(scryptKDF{})
+	RegisterKDF(argon2idPIMMemoryKDF{})
+}
+
+func RegisterKDF(kdf KDF) {
+	kdfRegistryMu.Lock()
+	defer kdfRegistryMu.Unlock()
+	kdfRegistry[kdf.ID()] = kdf
+}
+
+func LookupKDF(id string) (KDF, error) {
+	if id == "" {
+		id = KDFArgon2id
+	}
+	kdfRegistryMu.RLock()
+	defer kdfRegistryMu.RUnlock()
+	kdf, ok := kdfRegistry[id]
+	if !ok {
+		return nil, fmt.Errorf("unsupported KDF %q", id)
+	}
+	return kdf, nil
+}
+
+func NewKDFParamsFor(id string, salt []byte) (*KDFParams, error) {
+	kdf, err := LookupKDF(id)
+	if err != nil {
+		return nil, err
+	}
+	return kdf.DefaultParams(salt), nil
+}
+
+type argon2idKDF struct{}
+
+func (argon2idKDF) ID() string { return KDFArgon2id }
+
+func (argon2idKDF) DefaultParams(salt []byte) *KDFParams {
+	return &KDFParams{
+		KDF:       KDFArgon2id,
+		Salt:      salt,
+		Time:      Argon2DefaultTime,
+		Memory:    Argon2DefaultMemory,
+		Threads:   Argon2DefaultThreads,
+		KeyLength: keyMaterialLength,
+	}
+}
+
+func (argon2idKDF) Validate(params *KDFParams) error {
+	return validateArgon2Params(params)
+}
+
+func (argon2idKDF) Derive(password []byte, params *KDFParams, pim uint32) ([]byte, error) {
+	iterations, err := applyPIMIncrement(params.Time, pim)
+	if err != nil {
+		return nil, err
+	}
+	return argon2.IDKey(password, params.Salt, iterations, params.Memory, params.Threads, params.KeyLength), nil
+}
+
+func (k argon2idKDF) Upgrade(params *KDFParams) (*KDFParams, bool) {
+	return upgradeArgon2Params(k.ID(), params)
+}
+
+type argon2idPIMMemoryKDF struct{}
+
+func (argon2idPIMMemoryKDF) ID() string { return KDFArgon2idPIMMemory }
+
+func (argon2idPIMMemoryKDF) DefaultParams(salt []byte) *KDFParams {
+	params := argon2idKDF{}.DefaultParams(salt)
+	params.KDF = KDFArgon2idPIMMemory
+	return params
+}
+
+func (argon2idPIMMemoryKDF) Validate(params *KDFParams) error {
+	return validateArgon2Params(params)
+}
+
+func (argon2idPIMMemoryKDF) Derive(password []byte, params *KDFParams, pim uint32) ([]byte, error) {
+	if pim > maxPIMIncrement {
+		return nil, fmt.Errorf("pim %d exceeds maximum %d", pim, maxPIMIncrement)
+	}
+	memory := uint64(params.Memory) + uint64(pim)*pimMemoryStep
+	if memory > maxArgon2Memory {
+		return nil, fmt.Errorf("pim %d exceeds the memory limit", pim)
+	}
+	return argon2.IDKey(password, params.Salt, params.Time, uint32(memory), params.Threads, params.KeyLength), nil
+}
+
+func (k argon2idPIMMemoryKDF) Upgrade(params *KDFParams) (*KDFParams, bool) {
+	return upgradeArgon2Params(k.ID(), params)
+}
+
+type scryptKDF struct{}
+
+func (scryptKDF) ID() string { return KDFScrypt }
+
+func (scryptKDF) DefaultParams(salt []byte) *KDFParams {
+	return &KDFParams{
+		KDF:       KDFScrypt,
+		Salt:      salt,
+		LogN:      ScryptDefaultLogN,
+		R:         ScryptDefaultR,
+		P:         ScryptDefaultP,
+		KeyLength: keyMaterialLength,
+	}
+}
+
+func (scryptKDF) Validate(params *KDFParams) error {
+	if params.LogN == 0 || params.LogN > scryptMaxLogN || params.R == 0 || params.P == 0 {
+		return errors.New("invalid KDF parameters")
+	}
+	if uint64(params.R)*uint64(params.P) >= scryptMaxBlockCost {
+		return errors.New("invalid KDF parameters")
+	}
+	return nil
+}
+
+func (k scryptKDF) Derive(password []byte, params *KDFParams, pim uint32) ([]byte, error) {
+	if pim > maxPIMIncrement {
+		return nil, fmt.Errorf("pim %d exceeds maximum %d", pim, maxPIMIncrement)
+	}
+	next := *params
+	next.P = params.P + pim
+	if err := k.Validate(&next); err != nil {
+		return nil, fmt.Errorf("pim %d causes parallelism overflow", pim)
+	}
+	return scrypt.Key(password, params.Salt, 1<<params.LogN, int(params.R), int(next.P), int(params.KeyLength))
+}
+
+func (scryptKDF) Upgrade(params *KDFParams) (*KDFParams, bool) {
+	if params.LogN >= ScryptDefaultLogN && params.R >= ScryptDefaultR {
+		return params, false
+	}
+	next := cloneParams(params)
+	if next.LogN < ScryptDefaultLogN {
+		next.LogN = ScryptDefaultLogN
+	}
+	if next.R < ScryptDefaultR {
+		next.R = ScryptDefaultR
+	}
+	return next, true
+}
+
+func validateArgon2Params(params *KDFParams) error {
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return errors.New("invalid KDF parameters")
+	}
+	if params.Memory > maxArgon2Memory {
+		return errors.New("KDF memory cost is too high")
+	}
+	return nil
+}
+
+func upgradeArgon2Params(id string, params *KDFParams) (*KDFParams, bool) {
+	if (params.KDF == id || params.KDF == "") && uint64(params.Memory)*uint64(params.Time) >= argon2DefaultCost {
+		return params, false
+	}
+	next := cloneParams(params)
+	next.KDF = id
+	raiseArgon2Cost(next)
+	return next, true
+}
+
+func raiseArgon2Cost(params *KDFParams) {
+	if params.Memory < Argon2DefaultMemory {
+		params.Memory = Argon2DefaultMemory
+	}
+	if floor := argon2TimeFloor(params.Memory); params.Time < floor {
+		params.Time = floor
+	}
+}
+
+func argon2TimeFloor(memory uint32) uint32 {
+	return uint32((argon2DefaultCost + uint64(memory) - 1) / uint64(memory))
+}
@@ -0,0 +1,184 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	MaxKeySlots           = 8
+	keySlotIDLength       = 8
+	keySlotInfoLabel      = kdfInfoLabel + "/key-slot/"
+	wrappedVaultKeyLength = chacha20poly1305.NonceSizeX + vaultSecretLength + chacha20poly1305.Overhead
+)
+
+type KeySlot struct {
+	ID         string     `json:"id"`
+	Label      string     `json:"label"`
+	Params     *KDFParams `json:"params"`
+	PIM        uint32     `json:"pim,omitempty"`
+	Keyfiles   bool       `json:"keyfiles,omitempty"`
+	WrappedKey []byte     `json:"wrapped_key"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type VaultKey struct {
+	secret  []byte
+	slot    int
+	enclave *Enclave
+}
+
+func UnwrapVaultKey(password []byte, keyfiles [][]byte, pim uint32, meta *KDFMetadata) (*VaultKey, error) {
+	secret, slotIndex, err := unwrapVaultSecret(password, keyfiles, pim, meta)
+	if err != nil {
+		return nil, err
+	}
+	return newVaultKey(secret, slotIndex)
+}
+
+func DeriveKeyScheduleFromVaultKey(key *VaultKey, meta *KDFMetadata) (*KeySchedule, error) {
+	if key == nil || key.secret == nil {
+		return nil, errors.New("vault key has been wiped")
+	}
+	if err := validateMetadata(meta); err != nil {
+		return nil, err
+	}
+	return deriveKeySchedule(key.secret, meta.HKDFSalt, meta.WrappedMasterKey)
+}
+
+func newVaultKey(secret []byte, slot int) (*VaultKey, error) {
+	defer WipeBytes(secret)
+	enclave, err := NewEnclave(len(secret))
+	if err != nil {
+		return nil, err
+	}
+	data, err := enclave.Open()
+	if err != nil {
+		enclave.Destroy()
+		return nil, err
+	}
+	copy(data, secret)
+	return &VaultKey{secret: data, slot: slot, enclave: enclave}, nil
+}
+
+func (vk *VaultKey) Locked() bool {
+	return vk != nil && vk.enclave.Locked()
+}
+
+func (vk *VaultKey) Wipe() {
+	if vk == nil {
+		return
+	}
+	vk.enclave.Destroy()
+	vk.secret = nil
+}
+
+func NewKeySlot(key *VaultKey, label string, password []byte, keyfiles [][]byte, pim uint32, params *KDFParams) (*KeySlot, error) {
+	if key == nil {
+		return nil, errors.New("vault key cannot be nil")
+	}
+	if len(password) == 0 && len(keyfiles) == 0 {
+		return nil, errors.New("password or keyfile required")
+	}
+	if params == nil {
+		return nil, errors.New("KDF parameters cannot be nil")
+	}
+
+	idBytes, err := randomBytes(keySlotIDLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealKeySlot(key.secret, hex.EncodeToString(idBytes), label, password, keyfiles, pim, params)
+}
+
+func sealKeySlot(secret []byte, id, label string, password []byte, keyfiles [][]byte, pim uint32, params *KDFParams) (*KeySlot, error) {
+	salt, err := randomBytes(SaltLength)
+	if err != nil {
+		return nil, err
+	}
+	slotParams := explicitKDFParams(params)
+	slotParams.Salt = salt
+	if err := validateParams(slotParams); err != nil {
+		return nil, err
+	}
+
+	combinedPassword, keyfileDigest, err := combinePasswordAndKeyfiles(password, keyfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(combinedPassword)
+	defer WipeBytes(keyfileDigest)
+
+	verifier, kek, err := derivePasswordKey(combinedPassword, slotParams, pim)
+	if err != nil {
+		return nil, err
+	}
+	WipeBytes(verifier)
+	defer WipeBytes(kek)
+
+	wrapped, err := sealVaultSecret(kek, secret, keySlotInfoLabel+id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySlot{
+		ID:         id,
+		Label:      label,
+		Params:     slotParams,
+		PIM:        pim,
+		Keyfiles:   keyfileDigest != nil,
+		WrappedKey: wrapped,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+func openKeySlot(slot *KeySlot, password []byte, keyfiles [][]byte, pim uint32) ([]byte, error) {
+	if err := validateKeySlot(slot); err != nil {
+		return nil, err
+	}
+
+	combinedPassword, keyfileDigest, err := combinePasswordAndKeyfiles(password, keyfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(combinedPassword)
+	defer WipeBytes(keyfileDigest)
+
+	effectivePIM := slot.PIM
+	if pim != 0 {
+		effectivePIM = pim
+	}
+
+	verifier, kek, err := derivePasswordKey(combinedPassword, slot.Params, effectivePIM)
+	if err != nil {
+		return nil, err
+	}
+	WipeBytes(verifier)
+	defer WipeBytes(kek)
+
+	secret, err := openVaultSecret(kek, slot.WrappedKey, keySlotInfoLabel+slot.ID)
+	if err != nil {
+		return nil, errors.New("invalid password")
+	}
+	return secret, nil
+}
+
+func validateKeySlot(slot *KeySlot) error {
+	if slot == nil || len(slot.ID) == 0 {
+		return errors.New("invalid key slot")
+	}
+	if slot.Params == nil {
+		return errors.New("key slot params cannot be nil")
+	}
+	if err := validateParams(slot.Params); err != nil {
+		return err
+	}
+	if len(slot.WrappedKey) != wrappedVaultKeyLength {
+		return errors.New("invalid wrapped key length")
+	}
+	return nil
+}
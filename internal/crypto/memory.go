@@ -26,14 +26,6 @@ func WipeBytes(data []byte) {
 	}
 }
 
-func WipeString(s string) {
-	if len(s) == 0 {
-		return
-	}
-	data := []byte(s)
-	WipeBytes(data)
-}
-
 type SecureBuffer struct {
 	data []byte
 }
@@ -62,24 +54,24 @@ func (sb *SecureBuffer) Destroy() {
 	sb.data = nil
 }
 
-func (sb *SecureBuffer) Lock() {
+func (sb *SecureBuffer) Lock() error {
 	if sb == nil {
-		return
+		return nil
 	}
-	lockMemory(sb.data)
+	return lockMemory(sb.data)
 }
 
-func (sb *SecureBuffer) Unlock() {
+func (sb *SecureBuffer) Unlock() error {
 	if sb == nil {
-		return
+		return nil
 	}
-	unlockMemory(sb.data)
+	return unlockMemory(sb.data)
 }
 
-func LockBytes(data []byte) {
-	lockMemory(data)
+func LockBytes(data []byte) error {
+	return lockMemory(data)
 }
 
-func UnlockBytes(data []byte) {
-	unlockMemory(data)
+func UnlockBytes(data []byte) error {
+	return unlockMemory(data)
 }
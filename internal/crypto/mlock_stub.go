@@ -1,7 +1,13 @@
-//go:build windows || plan9
+//go:build plan9
 
 package crypto
 
-func lockMemory(data []byte) {}
+import "errors"
 
-func unlockMemory(data []byte) {}
+func lockMemory(data []byte) error {
+	return errors.New("memory locking is not supported on this platform")
+}
+
+func unlockMemory(data []byte) error {
+	return nil
+}
@@ -4,16 +4,16 @@ package crypto
 
 import "golang.org/x/sys/unix"
 
-func lockMemory(data []byte) {
+func lockMemory(data []byte) error {
 	if len(data) == 0 {
-		return
+		return nil
 	}
-	_ = unix.Mlock(data)
+	return unix.Mlock(data)
 }
 
-func unlockMemory(data []byte) {
+func unlockMemory(data []byte) error {
 	if len(data) == 0 {
-		return
+		return nil
 	}
-	_ = unix.Munlock(data)
+	return unix.Munlock(data)
 }
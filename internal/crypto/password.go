@@ -3,6 +3,7 @@ package crypto
 import (
 	"fmt"
 	"unicode"
+	"unicode/utf8"
 )
 
 type PasswordStrength int
@@ -33,7 +34,7 @@ func DefaultPasswordRequirements() *PasswordRequirements {
 	}
 }
 
-func ValidatePassword(password string, reqs *PasswordRequirements) (bool, []string) {
+func ValidatePassword(password []byte, reqs *PasswordRequirements) (bool, []string) {
 	var issues []string
 
 	if len(password) < reqs.MinLength {
@@ -45,7 +46,9 @@ func ValidatePassword(password string, reqs *PasswordRequirements) (bool, []stri
 	hasNumber := false
 	hasSpecial := false
 
-	for _, char := range password {
+	for rest := password; len(rest) > 0; {
+		char, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
 		if unicode.IsUpper(char) {
 			hasUpper = true
 		}
@@ -76,7 +79,7 @@ func ValidatePassword(password string, reqs *PasswordRequirements) (bool, []stri
 	return len(issues) == 0, issues
 }
 
-func GetPasswordStrength(password string) PasswordStrength {
+func GetPasswordStrength(password []byte) PasswordStrength {
 	score := 0
 
 	if len(password) >= 8 {
@@ -94,7 +97,9 @@ func GetPasswordStrength(password string) PasswordStrength {
 	hasNumber := false
 	hasSpecial := false
 
-	for _, char := range password {
+	for rest := password; len(rest) > 0; {
+		char, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
 		if unicode.IsUpper(char) {
 			hasUpper = true
 		}
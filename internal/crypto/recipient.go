@@ -0,0 +1,219 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	HybridStanzaType = "mlkem768x25519"
+	RecipientPrefix  = "micrypt1pq"
+	IdentityPrefix   = "MICRYPT-PQ-SECRET-KEY-1"
+
+	x25519KeyLength     = 32
+	identityLength      = x25519KeyLength + mlkem.SeedSize
+	recipientLength     = x25519KeyLength + mlkem.EncapsulationKeySize768
+	hybridStanzaLabel   = "micrypt/v2/hybrid-stanza"
+	maxStanzaFileKeyLen = 64
+)
+
+type Identity struct {
+	x25519 *ecdh.PrivateKey
+	mlkem  *mlkem.DecapsulationKey768
+}
+
+type Recipient struct {
+	x25519 *ecdh.PublicKey
+	mlkem  *mlkem.EncapsulationKey768
+}
+
+type Stanza struct {
+	Type       string `json:"type"`
+	Ephemeral  []byte `json:"ephemeral"`
+	Ciphertext []byte `json:"ciphertext"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+func GenerateIdentity() (*Identity, error) {
+	x, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	dk, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{x25519: x, mlkem: dk}, nil
+}
+
+func ParseIdentityBytes(data []byte) (*Identity, error) {
+	if len(data) != identityLength {
+		return nil, errors.New("invalid identity length")
+	}
+	x, err := ecdh.X25519().NewPrivateKey(data[:x25519KeyLength])
+	if err != nil {
+		return nil, errors.New("invalid identity")
+	}
+	dk, err := mlkem.NewDecapsulationKey768(data[x25519KeyLength:])
+	if err != nil {
+		return nil, errors.New("invalid identity")
+	}
+	return &Identity{x25519: x, mlkem: dk}, nil
+}
+
+func ParseIdentity(encoded string) (*Identity, error) {
+	data, err := decodeKey(encoded, IdentityPrefix)
+	if err != nil {
+		return nil, errors.New("invalid identity encoding")
+	}
+	defer WipeBytes(data)
+	return ParseIdentityBytes(data)
+}
+
+func (id *Identity) Bytes() []byte {
+	data := make([]byte, 0, identityLength)
+	data = append(data, id.x25519.Bytes()...)
+	return append(data, id.mlkem.Bytes()...)
+}
+
+func (id *Identity) String() string {
+	data := id.Bytes()
+	defer WipeBytes(data)
+	return IdentityPrefix + "-" + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (id *Identity) Recipient() *Recipient {
+	return &Recipient{x25519: id.x25519.PublicKey(), mlkem: id.mlkem.EncapsulationKey()}
+}
+
+func ParseRecipient(encoded string) (*Recipient, error) {
+	data, err := decodeKey(encoded, RecipientPrefix)
+	if err != nil || len(data) != recipientLength {
+		return nil, errors.New("invalid recipient encoding")
+	}
+	x, err := ecdh.X25519().NewPublicKey(data[:x25519KeyLength])
+	if err != nil {
+		return nil, errors.New("invalid recipient")
+	}
+	ek, err := mlkem.NewEncapsulationKey768(data[x25519KeyLength:])
+	if err != nil {
+		return nil, errors.New("invalid recipient")
+	}
+	return &Recipient{x25519: x, mlkem: ek}, nil
+}
+
+func (r *Recipient) Bytes() []byte {
+	data := make([]byte, 0, recipientLength)
+	data = append(data, r.x25519.Bytes()...)
+	return append(data, r.mlkem.Bytes()...)
+}
+
+func (r *Recipient) String() string {
+	return RecipientPrefix + "-" + base64.RawURLEncoding.EncodeToString(r.Bytes())
+}
+
+func WrapForRecipient(r *Recipient, fileKey []byte) (*Stanza, error) {
+	if r == nil {
+		return nil, errors.New("recipient cannot be nil")
+	}
+	if len(fileKey) == 0 || len(fileKey) > maxStanzaFileKeyLen {
+		return nil, errors.New("invalid file key length")
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	classical, err := ephemeral.ECDH(r.x25519)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(classical)
+	quantum, ciphertext := r.mlkem.Encapsulate()
+	defer WipeBytes(quantum)
+
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+	aead, err := newStanzaAEAD(quantum, classical, ciphertext, ephemeralPub, r.x25519.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	return &Stanza{
+		Type:       HybridStanzaType,
+		Ephemeral:  ephemeralPub,
+		Ciphertext: ciphertext,
+		WrappedKey: aead.Seal(nil, nonce, fileKey, nil),
+	}, nil
+}
+
+func UnwrapStanza(id *Identity, stanza *Stanza) ([]byte, error) {
+	if id == nil || stanza == nil {
+		return nil, errors.New("identity and stanza required")
+	}
+	if stanza.Type != HybridStanzaType {
+		return nil, errors.New("unsupported stanza type")
+	}
+	if len(stanza.Ephemeral) != x25519KeyLength || len(stanza.Ciphertext) != mlkem.CiphertextSize768 {
+		return nil, errors.New("invalid stanza")
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(stanza.Ephemeral)
+	if err != nil {
+		return nil, errors.New("invalid stanza")
+	}
+	classical, err := id.x25519.ECDH(ephemeral)
+	if err != nil {
+		return nil, errors.New("invalid stanza")
+	}
+	defer WipeBytes(classical)
+	quantum, err := id.mlkem.Decapsulate(stanza.Ciphertext)
+	if err != nil {
+		return nil, errors.New("invalid stanza")
+	}
+	defer WipeBytes(quantum)
+
+	aead, err := newStanzaAEAD(quantum, classical, stanza.Ciphertext, stanza.Ephemeral, id.x25519.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	fileKey, err := aead.Open(nil, nonce, stanza.WrappedKey, nil)
+	if err != nil {
+		return nil, errors.New("stanza is not addressed to this identity")
+	}
+	return fileKey, nil
+}
+
+func newStanzaAEAD(quantum, classical, ciphertext, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	ikm := make([]byte, 0, len(quantum)+len(classical))
+	ikm = append(ikm, quantum...)
+	ikm = append(ikm, classical...)
+	defer WipeBytes(ikm)
+
+	salt := make([]byte, 0, len(ciphertext)+len(ephemeral)+len(recipient))
+	salt = append(salt, ciphertext...)
+	salt = append(salt, ephemeral...)
+	salt = append(salt, recipient...)
+
+	key, err := deriveHKDFKey(ikm, salt, hybridStanzaLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(key)
+	return chacha20poly1305.New(key)
+}
+
+func decodeKey(encoded, prefix string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if len(encoded) <= len(prefix)+1 || encoded[:len(prefix)] != prefix || encoded[len(prefix)] != '-' {
+		return nil, errors.New("unexpected key prefix")
+	}
+	return base64.RawURLEncoding.DecodeString(encoded[len(prefix)+1:])
+}
@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHybridStanzaRoundTrip(t *testing.T) {
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	other, err := GenerateIdentity()
+	if err != nil {
+		t.Fatalf("generate other identity: %v", err)
+	}
+
+	recipient, err := ParseRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatalf("parse recipient: %v", err)
+	}
+	fileKey := bytes.Repeat([]byte{0x5a}, DataKeyLength)
+	stanza, err := WrapForRecipient(recipient, fileKey)
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+
+	restored, err := ParseIdentity(identity.String())
+	if err != nil {
+		t.Fatalf("parse identity: %v", err)
+	}
+	unwrapped, err := UnwrapStanza(restored, stanza)
+	if err != nil {
+		t.Fatalf("unwrap: %v", err)
+	}
+	if !bytes.Equal(unwrapped, fileKey) {
+		t.Fatal("unwrapped file key differs")
+	}
+	if _, err := UnwrapStanza(other, stanza); err == nil {
+		t.Fatal("expected other identity to be rejected")
+	}
+
+	tampered := *stanza
+	tampered.Ciphertext = append([]byte(nil), stanza.Ciphertext...)
+	tampered.Ciphertext[0] ^= 1
+	if _, err := UnwrapStanza(identity, &tampered); err == nil {
+		t.Fatal("expected tampered ML-KEM ciphertext to be rejected")
+	}
+	if _, err := ParseRecipient(identity.String()); err == nil {
+		t.Fatal("expected identity string to be rejected as recipient")
+	}
+}
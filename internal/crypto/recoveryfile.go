@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+const (
+	RecoveryFilePrefix       = "MICRYPT-RECOVERY-KEY-1-"
+	recoveryFileSecretLength = 32
+	recoveryFileInfoLabel    = kdfInfoLabel + "/recovery-file"
+)
+
+type RecoveryFileSlot struct {
+	Salt       []byte    `json:"salt"`
+	WrappedKey []byte    `json:"wrapped_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewRecoveryFileSlot(key *VaultKey) (*RecoveryFileSlot, []byte, error) {
+	if key == nil {
+		return nil, nil, errors.New("vault key cannot be nil")
+	}
+
+	secret, err := randomBytes(recoveryFileSecretLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeBytes(secret)
+	salt, err := randomBytes(SaltLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kek, err := deriveHKDFKey(secret, salt, recoveryFileInfoLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeBytes(kek)
+	wrapped, err := sealVaultSecret(kek, key.secret, recoveryFileInfoLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slot := &RecoveryFileSlot{
+		Salt:       salt,
+		WrappedKey: wrapped,
+		CreatedAt:  time.Now(),
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return slot, []byte(RecoveryFilePrefix + encoded + "\n"), nil
+}
+
+func DeriveKeyScheduleFromRecoveryFile(data []byte, meta *KDFMetadata) (*KeySchedule, error) {
+	vaultSecret, err := unwrapRecoveryFileSecret(data, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(vaultSecret)
+	return deriveKeySchedule(vaultSecret, meta.HKDFSalt, meta.WrappedMasterKey)
+}
+
+func ResetPasswordWithRecoveryFile(data []byte, newPassword []byte, newKeyfiles [][]byte, newPIM uint32, meta *KDFMetadata) (*KDFMetadata, error) {
+	if len(newPassword) == 0 && len(newKeyfiles) == 0 {
+		return nil, errors.New("new password or keyfile required")
+	}
+
+	vaultSecret, err := unwrapRecoveryFileSecret(data, meta)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(vaultSecret)
+
+	return sealPasswordKeys(vaultSecret, newPassword, newKeyfiles, newPIM, meta.Params, meta)
+}
+
+func unwrapRecoveryFileSecret(data []byte, meta *KDFMetadata) ([]byte, error) {
+	if err := validateMetadata(meta); err != nil {
+		return nil, err
+	}
+	slot := meta.RecoveryFile
+	if slot == nil {
+		return nil, errors.New("vault has no recovery file")
+	}
+	if len(slot.Salt) != SaltLength || len(slot.WrappedKey) != wrappedVaultKeyLength {
+		return nil, errors.New("invalid recovery file slot")
+	}
+
+	secret, err := parseRecoveryFile(data)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(secret)
+
+	kek, err := deriveHKDFKey(secret, slot.Salt, recoveryFileInfoLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(kek)
+	vaultSecret, err := openVaultSecret(kek, slot.WrappedKey, recoveryFileInfoLabel)
+	if err != nil {
+		return nil, errors.New("recovery file does not match this vault")
+	}
+	return vaultSecret, nil
+}
+
+func parseRecoveryFile(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte(RecoveryFilePrefix)) {
+		return nil, errors.New("not a micrypt recovery file")
+	}
+	encoded := trimmed[len(RecoveryFilePrefix):]
+	secret := make([]byte, base64.RawURLEncoding.DecodedLen(len(encoded)))
+	n, err := base64.RawURLEncoding.Decode(secret, encoded)
+	if err != nil || n != recoveryFileSecretLength {
+		WipeBytes(secret)
+		return nil, errors.New("recovery file is malformed")
+	}
+	return secret[:n], nil
+}
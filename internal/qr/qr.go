@@ -0,0 +1,480 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MinVersion = 1
+	MaxVersion = 20
+
+	formatGenerator  = 0x537
+	formatMask       = 0x5412
+	versionGenerator = 0x1f25
+	levelMBits       = 0
+)
+
+type blockLayout struct {
+	ecPerBlock int
+	groups     [][2]int
+}
+
+var levelMLayouts = [MaxVersion + 1]blockLayout{
+	1:  {10, [][2]int{{1, 16}}},
+	2:  {16, [][2]int{{1, 28}}},
+	3:  {26, [][2]int{{1, 44}}},
+	4:  {18, [][2]int{{2, 32}}},
+	5:  {24, [][2]int{{2, 43}}},
+	6:  {16, [][2]int{{4, 27}}},
+	7:  {18, [][2]int{{4, 31}}},
+	8:  {22, [][2]int{{2, 38}, {2, 39}}},
+	9:  {22, [][2]int{{3, 36}, {2, 37}}},
+	10: {26, [][2]int{{4, 43}, {1, 44}}},
+	11: {30, [][2]int{{1, 50}, {4, 51}}},
+	12: {22, [][2]int{{6, 36}, {2, 37}}},
+	13: {22, [][2]int{{8, 37}, {1, 38}}},
+	14: {24, [][2]int{{4, 40}, {5, 41}}},
+	15: {24, [][2]int{{5, 41}, {5, 42}}},
+	16: {28, [][2]int{{7, 45}, {3, 46}}},
+	17: {28, [][2]int{{10, 46}, {1, 47}}},
+	18: {26, [][2]int{{9, 43}, {4, 44}}},
+	19: {26, [][2]int{{3, 44}, {11, 45}}},
+	20: {26, [][2]int{{3, 41}, {13, 42}}},
+}
+
+type Code struct {
+	Version int
+	Size    int
+	modules []bool
+}
+
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+func (c *Code) SVG(moduleSize int) string {
+	if moduleSize < 1 {
+		moduleSize = 1
+	}
+	const quiet = 4
+	total := (c.Size + 2*quiet) * moduleSize
+
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Black(x, y) {
+				fmt.Fprintf(&path, "M%d %dh%dv%dh-%dz", (x+quiet)*moduleSize, (y+quiet)*moduleSize, moduleSize, moduleSize, moduleSize)
+			}
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges"><rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`,
+		total, total, total, total, path.String())
+}
+
+func Encode(data []byte) (*Code, error) {
+	version := 0
+	for v := MinVersion; v <= MaxVersion; v++ {
+		if 4+countBits(v)+8*len(data) <= 8*dataCapacity(v) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, errors.New("data is too long for a QR code")
+	}
+
+	codewords := interleave(version, encodeData(version, data))
+	code := &Code{Version: version, Size: version*4 + 17}
+	code.modules = make([]bool, code.Size*code.Size)
+	function := make([]bool, code.Size*code.Size)
+	code.drawFunctionPatterns(function)
+	code.drawCodewords(codewords, function)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		code.applyMask(mask, function)
+		code.drawFormatBits(mask, function)
+		if penalty := code.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		code.applyMask(mask, function)
+	}
+	code.applyMask(best, function)
+	code.drawFormatBits(best, function)
+	return code, nil
+}
+
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+func dataCapacity(version int) int {
+	total := 0
+	for _, group := range levelMLayouts[version].groups {
+		total += group[0] * group[1]
+	}
+	return total
+}
+
+func encodeData(version int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := dataCapacity(version) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	out := bits.bytes()
+	for pad := byte(0xec); len(out) < capacity/8; pad ^= 0xec ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+func interleave(version int, data []byte) []byte {
+	layout := levelMLayouts[version]
+	generator := rsGenerator(layout.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for _, group := range layout.groups {
+		for i := 0; i < group[0]; i++ {
+			block := data[offset : offset+group[1]]
+			offset += group[1]
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, generator))
+		}
+	}
+
+	out := make([]byte, 0, len(data)+len(ecBlocks)*layout.ecPerBlock)
+	longest := len(dataBlocks[len(dataBlocks)-1])
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+func (c *Code) set(x, y int, black bool, function []bool) {
+	c.modules[y*c.Size+x] = black
+	function[y*c.Size+x] = true
+}
+
+func (c *Code) drawFunctionPatterns(function []bool) {
+	for i := 0; i < c.Size; i++ {
+		c.set(6, i, i%2 == 0, function)
+		c.set(i, 6, i%2 == 0, function)
+	}
+
+	c.drawFinder(3, 3, function)
+	c.drawFinder(c.Size-4, 3, function)
+	c.drawFinder(3, c.Size-4, function)
+
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1, function)
+				}
+			}
+		}
+	}
+
+	c.drawFormatBits(0, function)
+	c.drawVersion(function)
+}
+
+func (c *Code) drawFinder(cx, cy int, function []bool) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.set(x, y, dist != 2 && dist != 4, function)
+		}
+	}
+}
+
+func (c *Code) drawFormatBits(mask int, function []bool) {
+	data := levelMBits<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*formatGenerator
+	}
+	bits := (data<<10 | rem) ^ formatMask
+
+	bit := func(i int) bool { return bits>>uint(i)&1 != 0 }
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i), function)
+	}
+	c.set(8, 7, bit(6), function)
+	c.set(8, 8, bit(7), function)
+	c.set(7, 8, bit(8), function)
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i), function)
+	}
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i), function)
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i), function)
+	}
+	c.set(8, c.Size-8, true, function)
+}
+
+func (c *Code) drawVersion(function []bool) {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*versionGenerator
+	}
+	bits := c.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		black := bits>>uint(i)&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.set(a, b, black, function)
+		c.set(b, a, black, function)
+	}
+}
+
+func (c *Code) drawCodewords(codewords []byte, function []bool) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = c.Size - 1 - vert
+				}
+				if function[y*c.Size+x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y*c.Size+x] = codewords[i>>3]>>uint(7-i&7)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int, function []bool) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if function[y*c.Size+x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y*c.Size+x] = !c.modules[y*c.Size+x]
+			}
+		}
+	}
+}
+
+func (c *Code) penalty() int {
+	penalty := 0
+	line := make([]bool, c.Size)
+	for horizontal := 0; horizontal < 2; horizontal++ {
+		for a := 0; a < c.Size; a++ {
+			for b := 0; b < c.Size; b++ {
+				if horizontal == 0 {
+					line[b] = c.Black(b, a)
+				} else {
+					line[b] = c.Black(a, b)
+				}
+			}
+			penalty += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			black := c.Black(x, y)
+			if black {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size && black == c.Black(x+1, y) && black == c.Black(x, y+1) && black == c.Black(x+1, y+1) {
+				penalty += 3
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return penalty + k*10
+}
+
+func linePenalty(line []bool) int {
+	penalty := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+
+	pattern := []bool{true, false, true, true, true, false, true}
+	for i := 0; i+len(pattern) <= len(line); i++ {
+		matches := true
+		for j, want := range pattern {
+			if line[i+j] != want {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if lightRun(line, i-4, i) || lightRun(line, i+len(pattern), i+len(pattern)+4) {
+			penalty += 40
+		}
+	}
+	return penalty
+}
+
+func lightRun(line []bool, from, to int) bool {
+	for i := from; i < to; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, version*4+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range generator {
+			result[i] ^= gfMul(coef, factor)
+		}
+	}
+	return result
+}
+
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		z ^= (int(y) >> uint(i) & 1) * int(x)
+	}
+	return byte(z)
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, value>>uint(i)&1 != 0)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return out
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
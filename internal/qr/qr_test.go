@@ -0,0 +1,82 @@
+package qr
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomonMatchesSpecExample(t *testing.T) {
+	data := []byte{0x10, 0x20, 0x0c, 0x56, 0x61, 0x80, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11}
+	want := []byte{0xa5, 0x24, 0xd4, 0xc1, 0xed, 0x36, 0xc7, 0x87, 0x2c, 0x55}
+	if got := rsRemainder(data, rsGenerator(len(want))); !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+}
+
+func TestEncodeMnemonicFitsAndDrawsFinders(t *testing.T) {
+	words := strings.TrimSpace(strings.Repeat("abstract ", 24))
+	code, err := Encode([]byte(words))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if code.Size != code.Version*4+17 {
+		t.Fatalf("size %d does not match version %d", code.Size, code.Version)
+	}
+	for _, corner := range [][2]int{{0, 0}, {code.Size - 7, 0}, {0, code.Size - 7}} {
+		for i := 0; i < 7; i++ {
+			if !code.Black(corner[0]+i, corner[1]) || !code.Black(corner[0], corner[1]+i) {
+				t.Fatalf("finder pattern at %v is incomplete", corner)
+			}
+		}
+	}
+	if _, err := Encode(make([]byte, 1000)); err == nil {
+		t.Fatal("expected oversized data to be rejected")
+	}
+}
+
+func TestEncodeMatchesReferenceMatrices(t *testing.T) {
+	for _, tc := range []struct {
+		golden  string
+		version int
+		content string
+	}{
+		{"legal-will-v7.txt", 7, "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will"},
+		{"abandon-art-v10.txt", 10, strings.Repeat("abandon ", 23) + "art"},
+	} {
+		want, err := os.ReadFile(filepath.Join("testdata", tc.golden))
+		if err != nil {
+			t.Fatalf("read %s: %v", tc.golden, err)
+		}
+		code, err := Encode([]byte(tc.content))
+		if err != nil {
+			t.Fatalf("encode %s: %v", tc.golden, err)
+		}
+		if code.Version != tc.version {
+			t.Fatalf("%s: got version %d, want %d", tc.golden, code.Version, tc.version)
+		}
+		var got strings.Builder
+		for y := 0; y < code.Size; y++ {
+			for x := 0; x < code.Size; x++ {
+				if code.Black(x, y) {
+					got.WriteByte('#')
+				} else {
+					got.WriteByte('.')
+				}
+			}
+			got.WriteByte('\n')
+		}
+		wantRows := strings.Split(strings.TrimSpace(string(want)), "\n")
+		gotRows := strings.Split(strings.TrimSpace(got.String()), "\n")
+		if len(gotRows) != len(wantRows) {
+			t.Fatalf("%s: got %d rows, want %d", tc.golden, len(gotRows), len(wantRows))
+		}
+		for y := range wantRows {
+			if gotRows[y] != wantRows[y] {
+				t.Fatalf("%s: row %d differs\n got %s\nwant %s", tc.golden, y, gotRows[y], wantRows[y])
+			}
+		}
+	}
+}
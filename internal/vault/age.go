@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"micrypt/internal/crypto"
+)
+
+const ageFileSuffix = ".age"
+
+func (v *Vault) ExportEntryAsAge(encryptedName string, destPath string, recipients []string, passphrase []byte) error {
+	defer crypto.WipeBytes(passphrase)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if len(destPath) == 0 {
+		return errors.New("destination path cannot be empty")
+	}
+	if (len(recipients) == 0) == (len(passphrase) == 0) {
+		return errors.New("provide either age recipients or a passphrase")
+	}
+	parsed := make([]*crypto.AgeRecipient, 0, len(recipients))
+	for _, encoded := range recipients {
+		recipient, err := crypto.ParseAgeRecipient(encoded)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, recipient)
+	}
+
+	entry := v.getIndexEntry(encryptedName)
+	if entry == nil {
+		return errors.New("file not found in vault index")
+	}
+	cipherData, ok := v.fileData[encryptedName]
+	if !ok {
+		return errors.New("vault data missing for requested file")
+	}
+	if !v.verifyBlobMAC(entry, cipherData) {
+		return errors.New("ciphertext integrity check failed")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return errors.New("destination file already exists")
+	}
+	blobCipher, err := v.entryCipher(entry)
+	if err != nil {
+		return err
+	}
+
+	destFile, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(blobCipher.DecryptStreamVersion(bytes.NewReader(cipherData), pw, entry.streamVersion(), v.blobAssociatedData(entry)))
+	}()
+	if len(parsed) > 0 {
+		err = crypto.EncryptAge(destFile, pr, parsed...)
+	} else {
+		err = crypto.EncryptAgeWithPassphrase(destFile, pr, passphrase, crypto.AgeDefaultScryptLogN)
+	}
+	pr.CloseWithError(err)
+	if err != nil {
+		destFile.Close()
+		os.Remove(destPath)
+		return err
+	}
+	if err := destFile.Close(); err != nil {
+		os.Remove(destPath)
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) ImportAge(path string, identity string, passphrase []byte) (*FileEntry, error) {
+	defer crypto.WipeBytes(passphrase)
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+	if (len(identity) == 0) == (len(passphrase) == 0) {
+		return nil, errors.New("provide either an age identity or a passphrase")
+	}
+
+	source, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	var ageIdentity *crypto.AgeIdentity
+	if len(identity) > 0 {
+		ageIdentity, err = crypto.ParseAgeIdentity(identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	name := filepath.Base(path)
+	if trimmed := strings.TrimSuffix(name, ageFileSuffix); len(trimmed) > 0 {
+		name = trimmed
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		if ageIdentity != nil {
+			pw.CloseWithError(crypto.DecryptAge(pw, source, ageIdentity))
+		} else {
+			pw.CloseWithError(crypto.DecryptAgeWithPassphrase(pw, source, passphrase))
+		}
+	}()
+	entry, err := v.addEntry(pr, name, -1)
+	pr.CloseWithError(err)
+	return entry, err
+}
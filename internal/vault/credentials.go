@@ -0,0 +1,374 @@
+package vault
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"micrypt/internal/bip39"
+	"micrypt/internal/crypto"
+)
+
+const (
+	primaryKeySlotID    = "primary"
+	primaryKeySlotLabel = "Primary"
+	maxKeySlotLabel     = 64
+)
+
+type KeySlotInfo struct {
+	ID           string
+	Label        string
+	Primary      bool
+	UsesKeyfiles bool
+	CreatedAt    time.Time
+}
+
+func (v *Vault) ChangeCredentials(oldPassword []byte, oldOptions *UnlockOptions, newPassword []byte, newOptions *UnlockOptions) error {
+	var oldOpts, newOpts UnlockOptions
+	if oldOptions != nil {
+		oldOpts = *oldOptions
+	}
+	if newOptions != nil {
+		newOpts = *newOptions
+	}
+	defer wipeCredentials(oldPassword, oldOpts.Keyfiles)
+	defer wipeCredentials(newPassword, newOpts.Keyfiles)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if len(oldPassword) == 0 && len(oldOpts.Keyfiles) == 0 {
+		return errors.New("current password or keyfile required")
+	}
+	if err := validateNewCredentials(newPassword, newOpts.Keyfiles); err != nil {
+		return err
+	}
+
+	kdfMeta, err := crypto.RekeyPassword(oldPassword, oldOpts.Keyfiles, oldOpts.PIM, newPassword, newOpts.Keyfiles, newOpts.PIM, v.kdfMeta)
+	if err != nil {
+		return err
+	}
+
+	return v.replaceKDFMetadata(kdfMeta)
+}
+
+func (v *Vault) replaceKDFMetadata(kdfMeta *crypto.KDFMetadata) error {
+	previous := v.kdfMeta
+	v.kdfMeta = kdfMeta
+	if err := v.saveMetadata(); err != nil {
+		v.kdfMeta = previous
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) upgradeKDF(vaultKey *crypto.VaultKey, password []byte, options *UnlockOptions) error {
+	kdfMeta, changed, err := crypto.UpgradeKDF(vaultKey, password, options.Keyfiles, options.PIM, v.kdfMeta)
+	if err != nil || !changed {
+		return err
+	}
+	return v.replaceKDFMetadata(kdfMeta)
+}
+
+func wipeCredentials(password []byte, keyfiles [][]byte) {
+	crypto.WipeBytes(password)
+	for _, kf := range keyfiles {
+		crypto.WipeBytes(kf)
+	}
+}
+
+func validateNewCredentials(password []byte, keyfiles [][]byte) error {
+	if len(password) == 0 {
+		if len(keyfiles) == 0 {
+			return errors.New("password must be at least 8 characters or keyfiles required")
+		}
+	} else if len(password) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+	return nil
+}
+
+func RecoverVaultWithSeed(path string, mnemonicSeed []byte, newPassword []byte, options *UnlockOptions) (*Vault, error) {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(newPassword, opts.Keyfiles)
+	if err := validateNewCredentials(newPassword, opts.Keyfiles); err != nil {
+		return nil, err
+	}
+
+	v, err := OpenVaultFromMnemonicSeed(path, mnemonicSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.ResetCredentialsWithSeed(mnemonicSeed, newPassword, &opts); err != nil {
+		v.Lock()
+		return nil, err
+	}
+	return v, nil
+}
+
+func OpenVaultFromRecoveryShares(path string, shares [][]string, passphrase []byte) (*Vault, error) {
+	defer crypto.WipeBytes(passphrase)
+	words, err := bip39.CombineShares(shares)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := bip39.RestoreFromMnemonic(words, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(mnemonic.Seed)
+	return OpenVaultFromMnemonicSeed(path, mnemonic.Seed)
+}
+
+func (v *Vault) CreateRecoveryShares(password []byte, options *UnlockOptions, passphrase []byte, threshold, count int) ([][]string, error) {
+	defer crypto.WipeBytes(passphrase)
+	if err := v.VerifyPassword(password, options); err != nil {
+		return nil, err
+	}
+	words := v.StoredMnemonic()
+	if len(words) == 0 {
+		return nil, errors.New("recovery phrase is not stored in this vault")
+	}
+	mnemonic, err := bip39.RestoreFromMnemonicInLanguage(words, passphrase, v.MnemonicLanguage())
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(mnemonic.Seed)
+	if err := v.verifyMnemonicSeed(mnemonic.Seed); err != nil {
+		return nil, err
+	}
+	return bip39.SplitMnemonic(mnemonic.Words, threshold, count)
+}
+
+func (v *Vault) RotateRecoveryPhrase(password []byte, options *UnlockOptions, recoveryPassphrase []byte) (*bip39.Mnemonic, error) {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(password, opts.Keyfiles)
+	defer crypto.WipeBytes(recoveryPassphrase)
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+
+	wordCount := v.index.RecoveryWords
+	if wordCount == 0 {
+		wordCount = len(v.storedMnemonic)
+	}
+	bits, err := bip39.BitsForWordCount(wordCount)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := bip39.GenerateMnemonicInLanguage(bits, recoveryPassphrase, v.MnemonicLanguage())
+	if err != nil {
+		return nil, err
+	}
+	kdfMeta, err := crypto.RotateSeedKey(password, opts.Keyfiles, opts.PIM, mnemonic.Seed, v.kdfMeta)
+	if err != nil {
+		crypto.WipeBytes(mnemonic.Seed)
+		return nil, err
+	}
+
+	previousWords := v.StoredMnemonic()
+	previousPassphrase, previousWordCount := v.index.RecoveryPassphrase, v.index.RecoveryWords
+	if len(previousWords) > 0 {
+		v.SetStoredMnemonic(mnemonic.Words)
+	}
+	v.index.RecoveryPassphrase = len(recoveryPassphrase) > 0
+	v.index.RecoveryWords = len(mnemonic.Words)
+	if err := v.replaceKDFMetadata(kdfMeta); err != nil {
+		v.SetStoredMnemonic(previousWords)
+		v.index.RecoveryPassphrase, v.index.RecoveryWords = previousPassphrase, previousWordCount
+		crypto.WipeBytes(mnemonic.Seed)
+		return nil, err
+	}
+	return mnemonic, nil
+}
+
+func (v *Vault) ForgetRecoveryPhrase(password []byte, options *UnlockOptions) error {
+	if err := v.VerifyPassword(password, options); err != nil {
+		return err
+	}
+	previousWords := v.StoredMnemonic()
+	if len(previousWords) == 0 {
+		return nil
+	}
+	v.SetStoredMnemonic(nil)
+	if err := v.saveMetadata(); err != nil {
+		v.SetStoredMnemonic(previousWords)
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) MnemonicLanguage() string {
+	if v.header == nil || len(v.header.MnemonicLanguage) == 0 {
+		return bip39.LanguageEnglish
+	}
+	return v.header.MnemonicLanguage
+}
+
+func (v *Vault) HasStoredMnemonic() bool {
+	return v != nil && len(v.storedMnemonic) > 0
+}
+
+func (v *Vault) RecoveryUsesPassphrase() bool {
+	return v.unlocked && v.index != nil && v.index.RecoveryPassphrase
+}
+
+func (v *Vault) verifyMnemonicSeed(seed []byte) error {
+	matches := false
+	if keySchedule, err := crypto.DeriveKeyScheduleFromSeed(seed, v.kdfMeta); err == nil {
+		defer keySchedule.Wipe()
+		if err := v.withKeys(func(keys *crypto.KeySchedule) error {
+			matches = subtle.ConstantTimeCompare(keySchedule.MetadataKey, keys.MetadataKey) == 1
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	if !matches {
+		if v.index.RecoveryPassphrase {
+			return errors.New("recovery passphrase is incorrect")
+		}
+		return errors.New("recovery phrase does not match this vault")
+	}
+	return nil
+}
+
+func (v *Vault) ResetCredentialsWithSeed(mnemonicSeed []byte, newPassword []byte, options *UnlockOptions) error {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(newPassword, opts.Keyfiles)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if err := validateNewCredentials(newPassword, opts.Keyfiles); err != nil {
+		return err
+	}
+
+	kdfMeta, err := crypto.ResetPasswordWithSeed(mnemonicSeed, newPassword, opts.Keyfiles, opts.PIM, v.kdfMeta)
+	if err != nil {
+		return err
+	}
+
+	return v.replaceKDFMetadata(kdfMeta)
+}
+
+func (v *Vault) ListKeySlots() []KeySlotInfo {
+	if !v.unlocked || v.kdfMeta == nil {
+		return []KeySlotInfo{}
+	}
+	slots := make([]KeySlotInfo, 0, len(v.kdfMeta.KeySlots)+1)
+	slots = append(slots, KeySlotInfo{
+		ID:           primaryKeySlotID,
+		Label:        primaryKeySlotLabel,
+		Primary:      true,
+		UsesKeyfiles: len(v.kdfMeta.KeyfileVerifier) > 0,
+		CreatedAt:    v.header.CreatedAt,
+	})
+	for _, slot := range v.kdfMeta.KeySlots {
+		slots = append(slots, KeySlotInfo{
+			ID:           slot.ID,
+			Label:        slot.Label,
+			UsesKeyfiles: slot.Keyfiles,
+			CreatedAt:    slot.CreatedAt,
+		})
+	}
+	return slots
+}
+
+func (v *Vault) AddKeySlot(password []byte, options *UnlockOptions, label string, slotPassword []byte, slotOptions *UnlockOptions) (*KeySlotInfo, error) {
+	var opts, slotOpts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	if slotOptions != nil {
+		slotOpts = *slotOptions
+	}
+	defer wipeCredentials(password, opts.Keyfiles)
+	defer wipeCredentials(slotPassword, slotOpts.Keyfiles)
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+	label = strings.TrimSpace(label)
+	if len(label) == 0 {
+		return nil, errors.New("key slot label cannot be empty")
+	}
+	if len(label) > maxKeySlotLabel {
+		return nil, errors.New("key slot label is too long")
+	}
+	if len(v.kdfMeta.KeySlots) >= crypto.MaxKeySlots {
+		return nil, errors.New("all key slots are in use")
+	}
+	if err := validateNewCredentials(slotPassword, slotOpts.Keyfiles); err != nil {
+		return nil, err
+	}
+
+	vaultKey, err := crypto.UnwrapVaultKey(password, opts.Keyfiles, opts.PIM, v.kdfMeta)
+	if err != nil {
+		return nil, err
+	}
+	defer vaultKey.Wipe()
+
+	slot, err := crypto.NewKeySlot(vaultKey, label, slotPassword, slotOpts.Keyfiles, slotOpts.PIM, v.kdfMeta.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *v.kdfMeta
+	next.KeySlots = append(append([]crypto.KeySlot(nil), v.kdfMeta.KeySlots...), *slot)
+	if err := v.replaceKDFMetadata(&next); err != nil {
+		return nil, err
+	}
+
+	return &KeySlotInfo{
+		ID:           slot.ID,
+		Label:        slot.Label,
+		UsesKeyfiles: slot.Keyfiles,
+		CreatedAt:    slot.CreatedAt,
+	}, nil
+}
+
+func (v *Vault) RemoveKeySlot(password []byte, options *UnlockOptions, id string) error {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(password, opts.Keyfiles)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if id == primaryKeySlotID {
+		return errors.New("the primary key slot cannot be removed")
+	}
+
+	index := -1
+	for i, slot := range v.kdfMeta.KeySlots {
+		if slot.ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return errors.New("key slot not found")
+	}
+
+	vaultKey, err := crypto.UnwrapVaultKey(password, opts.Keyfiles, opts.PIM, v.kdfMeta)
+	if err != nil {
+		return err
+	}
+	vaultKey.Wipe()
+
+	next := *v.kdfMeta
+	next.KeySlots = make([]crypto.KeySlot, 0, len(v.kdfMeta.KeySlots)-1)
+	next.KeySlots = append(next.KeySlots, v.kdfMeta.KeySlots[:index]...)
+	next.KeySlots = append(next.KeySlots, v.kdfMeta.KeySlots[index+1:]...)
+	return v.replaceKDFMetadata(&next)
+}
@@ -36,6 +36,8 @@ func DeleteVault(path string) error {
 	if err := os.Remove(path); err != nil {
 		return err
 	}
+	_ = os.RemoveAll(path + reencryptDirSuffix)
+	_ = os.RemoveAll(path + dropboxDirSuffix)
 
 	dir := filepath.Dir(path)
 	if dir != "" {
@@ -0,0 +1,256 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"micrypt/internal/crypto"
+)
+
+const (
+	dropboxDirSuffix   = ".dropbox"
+	dropboxFileSuffix  = ".mcrb"
+	dropboxCascadeMode = crypto.XChaCha20
+)
+
+func AddToDropbox(path string, file string) error {
+	if len(path) == 0 {
+		return errors.New("vault path cannot be empty")
+	}
+	if len(file) == 0 {
+		return errors.New("source path cannot be empty")
+	}
+
+	metaFile, err := loadContainerMetadata(path)
+	if err != nil {
+		return err
+	}
+	if len(metaFile.DropboxRecipient) == 0 {
+		return errors.New("vault does not accept drop-box files")
+	}
+	recipient, err := crypto.ParseRecipient(metaFile.DropboxRecipient)
+	if err != nil {
+		return err
+	}
+
+	source, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	stat, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		return errors.New("cannot encrypt directories")
+	}
+
+	dir := path + dropboxDirSuffix
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	id, err := randomHexID()
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, id+dropboxFileSuffix)
+	return writeRecipientBundle([]*crypto.Recipient{recipient}, dropboxCascadeMode, filepath.Base(file), source, dest)
+}
+
+func (v *Vault) EnableDropbox() (string, error) {
+	if !v.unlocked {
+		return "", errors.New("vault is locked")
+	}
+	if v.index.Dropbox != nil {
+		return v.index.Dropbox.Recipient, nil
+	}
+
+	identity, err := crypto.GenerateIdentity()
+	if err != nil {
+		return "", err
+	}
+	stored := &StoredIdentity{
+		Label:     "Drop box",
+		Recipient: identity.Recipient().String(),
+		Secret:    identity.Bytes(),
+	}
+	if err := stored.guard(); err != nil {
+		stored.wipe()
+		return "", err
+	}
+	v.index.Dropbox = stored
+	if err := v.saveMetadata(); err != nil {
+		v.index.Dropbox = nil
+		stored.wipe()
+		return "", err
+	}
+	return v.index.Dropbox.Recipient, nil
+}
+
+func (v *Vault) DisableDropbox() error {
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if v.index.Dropbox == nil {
+		return nil
+	}
+	if pending, _ := pendingDropboxFiles(v.path); len(pending) > 0 {
+		return errors.New("ingest the pending drop-box files first")
+	}
+
+	previous := v.index.Dropbox
+	v.index.Dropbox = nil
+	if err := v.saveMetadata(); err != nil {
+		v.index.Dropbox = previous
+		return err
+	}
+	previous.wipe()
+	return nil
+}
+
+func (v *Vault) DropboxRecipient() string {
+	if !v.unlocked || v.index.Dropbox == nil {
+		return ""
+	}
+	return v.index.Dropbox.Recipient
+}
+
+func (v *Vault) IngestDropbox() (int, error) {
+	ingested, _, err := v.ingestDropbox()
+	return ingested, err
+}
+
+func (v *Vault) ingestDropboxOnUnlock() {
+	v.report.DropboxIngested, v.report.DropboxFailed, v.report.DropboxError = v.ingestDropbox()
+}
+
+func (v *Vault) ingestDropbox() (int, int, error) {
+	if !v.unlocked {
+		return 0, 0, errors.New("vault is locked")
+	}
+	if v.index.Dropbox == nil {
+		return 0, 0, nil
+	}
+	pending, err := pendingDropboxFiles(v.path)
+	if err != nil || len(pending) == 0 {
+		return 0, 0, err
+	}
+	identity, err := v.index.Dropbox.identity()
+	if err != nil {
+		return 0, len(pending), err
+	}
+
+	imported := make(map[string]bool, len(v.index.DropboxIngested))
+	for _, name := range v.index.DropboxIngested {
+		imported[name] = true
+	}
+
+	var entries []FileEntry
+	var blobs [][]byte
+	var names []string
+	var stale []string
+	var firstErr error
+	failed := 0
+	for _, file := range pending {
+		name := filepath.Base(file)
+		if imported[name] {
+			stale = append(stale, file)
+			continue
+		}
+		entry, data, err := v.sealDropboxFile(identity, file)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		entries = append(entries, *entry)
+		blobs = append(blobs, data)
+		names = append(names, name)
+	}
+
+	if len(entries) > 0 {
+		previousFiles := v.index.Files
+		previousIngested := v.index.DropboxIngested
+		previousModified := v.header.ModifiedAt
+		v.index.Files = append(append([]FileEntry(nil), previousFiles...), entries...)
+		v.index.DropboxIngested = nil
+		for _, file := range stale {
+			v.index.DropboxIngested = append(v.index.DropboxIngested, filepath.Base(file))
+		}
+		v.index.DropboxIngested = append(v.index.DropboxIngested, names...)
+		for i := range entries {
+			v.fileData[entries[i].EncryptedName] = blobs[i]
+		}
+		v.header.ModifiedAt = time.Now()
+		if err := v.saveMetadata(); err != nil {
+			v.index.Files = previousFiles
+			v.index.DropboxIngested = previousIngested
+			v.header.ModifiedAt = previousModified
+			for i := range entries {
+				delete(v.fileData, entries[i].EncryptedName)
+			}
+			wipeBlobList(blobs)
+			return 0, len(pending) - len(stale), err
+		}
+		for _, name := range names {
+			stale = append(stale, filepath.Join(v.path+dropboxDirSuffix, name))
+		}
+	}
+
+	removed := false
+	for _, file := range stale {
+		if err := os.Remove(file); err == nil {
+			removed = true
+		}
+	}
+	if removed {
+		_ = syncDirectory(v.path + dropboxDirSuffix)
+	}
+	return len(entries), failed, firstErr
+}
+
+func (v *Vault) sealDropboxFile(identity *crypto.Identity, file string) (*FileEntry, []byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	name, content, err := openRecipientBundle(data, []*crypto.Identity{identity})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer crypto.WipeBytes(content)
+	return v.sealEntry(bytes.NewReader(content), name, int64(len(content)))
+}
+
+func wipeBlobList(blobs [][]byte) {
+	for _, data := range blobs {
+		crypto.WipeBytes(data)
+	}
+}
+
+func pendingDropboxFiles(path string) ([]string, error) {
+	dir := path + dropboxDirSuffix
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), dropboxFileSuffix) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,289 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"micrypt/internal/crypto"
+)
+
+const dataKeyBindingLabel = "micrypt/data-key"
+
+type FileKey struct {
+	CascadeMode    crypto.CascadeMode
+	StreamVersion  int
+	Key            []byte
+	AssociatedData []byte
+}
+
+func (fk *FileKey) Wipe() {
+	if fk == nil {
+		return
+	}
+	crypto.WipeBytes(fk.Key)
+}
+
+func DecryptWithFileKey(fk *FileKey, ciphertext io.Reader, plaintext io.Writer) error {
+	if fk == nil || len(fk.Key) != crypto.DataKeyLength {
+		return errors.New("invalid file key")
+	}
+	blobCipher, err := crypto.NewCascadeCipher(fk.CascadeMode, fk.Key)
+	if err != nil {
+		return err
+	}
+	return blobCipher.DecryptStreamVersion(ciphertext, plaintext, fk.StreamVersion, fk.AssociatedData)
+}
+
+func (v *Vault) ExportFileKey(encryptedName string) (*FileKey, error) {
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+	entry := v.getIndexEntry(encryptedName)
+	if entry == nil {
+		return nil, errors.New("file not found in vault index")
+	}
+	if len(entry.WrappedKey) == 0 {
+		return nil, errors.New("file has no individual key; re-encrypt the vault first")
+	}
+
+	dataKey, err := v.unwrapEntryKey(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &FileKey{
+		CascadeMode:    v.header.CascadeMode,
+		StreamVersion:  entry.streamVersion(),
+		Key:            dataKey,
+		AssociatedData: v.blobAssociatedData(entry),
+	}, nil
+}
+
+func (v *Vault) ExportEncryptedFile(encryptedName string, destPath string) error {
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	entry := v.getIndexEntry(encryptedName)
+	if entry == nil {
+		return errors.New("file not found in vault index")
+	}
+	cipherData, ok := v.fileData[encryptedName]
+	if !ok {
+		return errors.New("vault data missing for requested file")
+	}
+	if !v.verifyBlobMAC(entry, cipherData) {
+		return errors.New("ciphertext integrity check failed")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return errors.New("destination file already exists")
+	}
+	return writeFileAtomic(destPath, cipherData, 0o600)
+}
+
+func (v *Vault) RotateMasterKey(password []byte, options *UnlockOptions) error {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(password, opts.Keyfiles)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if _, pending := v.PendingReencryption(); pending {
+		return errors.New("finish the pending re-encryption first")
+	}
+
+	keys, kdfMeta, err := crypto.RotateMasterKey(password, opts.Keyfiles, opts.PIM, v.kdfMeta)
+	if err != nil {
+		return err
+	}
+	defer keys.Wipe()
+	return v.replaceKeySchedule(keys, kdfMeta)
+}
+
+func (v *Vault) replaceKeySchedule(keys *crypto.KeySchedule, kdfMeta *crypto.KDFMetadata) error {
+	if _, err := v.ensureBindingIDs(); err != nil {
+		return err
+	}
+
+	nextEntries := append([]FileEntry(nil), v.index.Files...)
+	nextData := make(map[string][]byte, len(v.fileData))
+	for name, data := range v.fileData {
+		nextData[name] = data
+	}
+	reencrypted := make(map[string][]byte)
+	for i := range nextEntries {
+		entry := &v.index.Files[i]
+		next := &nextEntries[i]
+		cipherData, ok := v.fileData[entry.EncryptedName]
+		if !ok || !v.verifyBlobMAC(entry, cipherData) {
+			wipeBlobs(reencrypted)
+			return errors.New("ciphertext integrity check failed")
+		}
+		if len(entry.WrappedKey) == 0 {
+			data, err := v.reencryptUnderKey(keys.MasterKey, entry, next)
+			if err != nil {
+				wipeBlobs(reencrypted)
+				return err
+			}
+			reencrypted[entry.EncryptedName] = data
+			nextData[entry.EncryptedName] = data
+			cipherData = data
+		} else {
+			dataKey, err := v.unwrapEntryKey(entry)
+			if err != nil {
+				wipeBlobs(reencrypted)
+				return err
+			}
+			next.WrappedKey, err = crypto.WrapDataKey(keys.MasterKey, dataKey, v.dataKeyAssociatedData(entry))
+			crypto.WipeBytes(dataKey)
+			if err != nil {
+				wipeBlobs(reencrypted)
+				return err
+			}
+		}
+		next.CipherMAC = v.blobMACWithKey(keys.AuthKey, next, cipherData)
+	}
+
+	if _, err := crypto.NewCascadeCipher(v.header.CascadeMode, keys.MasterKey); err != nil {
+		wipeBlobs(reencrypted)
+		return err
+	}
+	nextKeys, err := keys.Clone()
+	if err != nil {
+		wipeBlobs(reencrypted)
+		return err
+	}
+	nextKeys.Seal()
+
+	previousEntries := append([]FileEntry(nil), v.index.Files...)
+	previousData, previousMeta, previousKeys := v.fileData, v.kdfMeta, v.keys
+
+	copy(v.index.Files, nextEntries)
+	v.fileData = nextData
+	v.kdfMeta = kdfMeta
+	v.keys = nextKeys
+
+	if err := v.saveMetadata(); err != nil {
+		copy(v.index.Files, previousEntries)
+		v.fileData, v.kdfMeta, v.keys = previousData, previousMeta, previousKeys
+		nextKeys.Wipe()
+		wipeBlobs(reencrypted)
+		return err
+	}
+
+	for name := range reencrypted {
+		crypto.WipeBytes(previousData[name])
+	}
+	previousKeys.Wipe()
+	return nil
+}
+
+func (v *Vault) reencryptUnderKey(masterKey []byte, entry *FileEntry, next *FileEntry) ([]byte, error) {
+	dataKey, err := crypto.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(dataKey)
+
+	target, err := crypto.NewCascadeCipher(v.header.CascadeMode, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	next.StreamVersion = crypto.CurrentStreamVersion
+	next.WrappedKey, err = crypto.WrapDataKey(masterKey, dataKey, v.dataKeyAssociatedData(next))
+	if err != nil {
+		return nil, err
+	}
+	return v.reencryptBlob(context.Background(), target, entry, next)
+}
+
+func wipeBlobs(blobs map[string][]byte) {
+	for _, data := range blobs {
+		crypto.WipeBytes(data)
+	}
+}
+
+func (v *Vault) withKeys(fn func(keys *crypto.KeySchedule) error) error {
+	if v.keys == nil {
+		return errors.New("vault is locked")
+	}
+	if err := v.keys.Open(); err != nil {
+		return err
+	}
+	defer v.keys.Seal()
+	return fn(v.keys)
+}
+
+func (v *Vault) unwrapEntryKey(entry *FileEntry) ([]byte, error) {
+	var dataKey []byte
+	err := v.withKeys(func(keys *crypto.KeySchedule) error {
+		var err error
+		dataKey, err = crypto.UnwrapDataKey(keys.MasterKey, entry.WrappedKey, v.dataKeyAssociatedData(entry))
+		return err
+	})
+	return dataKey, err
+}
+
+func (v *Vault) KeysLocked() bool {
+	return v.keys != nil && v.keys.Locked()
+}
+
+func (v *Vault) KeysGuarded() bool {
+	return v.keys != nil && v.keys.Guarded()
+}
+
+func (v *Vault) entryCipher(entry *FileEntry) (*crypto.CascadeCipher, error) {
+	if len(entry.WrappedKey) == 0 {
+		var legacy *crypto.CascadeCipher
+		err := v.withKeys(func(keys *crypto.KeySchedule) error {
+			var err error
+			legacy, err = crypto.NewCascadeCipher(v.header.CascadeMode, keys.MasterKey)
+			return err
+		})
+		return legacy, err
+	}
+	dataKey, err := v.unwrapEntryKey(entry)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(dataKey)
+	return crypto.NewCascadeCipher(v.header.CascadeMode, dataKey)
+}
+
+func (v *Vault) sealEntryKey(entry *FileEntry, mode crypto.CascadeMode) (*crypto.CascadeCipher, []byte, error) {
+	dataKey, err := crypto.NewDataKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer crypto.WipeBytes(dataKey)
+
+	blobCipher, err := crypto.NewCascadeCipher(mode, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	var wrapped []byte
+	err = v.withKeys(func(keys *crypto.KeySchedule) error {
+		wrapped, err = crypto.WrapDataKey(keys.MasterKey, dataKey, v.dataKeyAssociatedData(entry))
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return blobCipher, wrapped, nil
+}
+
+func (v *Vault) dataKeyAssociatedData(entry *FileEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(dataKeyBindingLabel)
+	for _, field := range []string{v.header.VaultID, entry.ID} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+		buf.Write(length[:])
+		buf.WriteString(field)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,434 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"micrypt/internal/crypto"
+)
+
+const (
+	recipientBundleMagic   = "MCRB"
+	recipientBundleVersion = 1
+	maxBundleHeaderSize    = 1 << 20
+	maxBundleNameLength    = 1<<16 - 1
+	maxIdentityLabel       = 64
+)
+
+type StoredIdentity struct {
+	ID        string
+	Label     string
+	Recipient string
+	Secret    []byte
+	CreatedAt time.Time
+
+	secret *crypto.Enclave
+}
+
+type IdentityInfo struct {
+	ID        string
+	Label     string
+	Recipient string
+	CreatedAt time.Time
+}
+
+type recipientBundleHeader struct {
+	CascadeMode   crypto.CascadeMode `json:"cascade_mode"`
+	StreamVersion int                `json:"stream_version"`
+	Stanzas       []crypto.Stanza    `json:"stanzas"`
+}
+
+func (v *Vault) ListIdentities() []IdentityInfo {
+	if !v.unlocked || v.index == nil {
+		return []IdentityInfo{}
+	}
+	identities := make([]IdentityInfo, 0, len(v.index.Identities))
+	for _, stored := range v.index.Identities {
+		identities = append(identities, stored.info())
+	}
+	return identities
+}
+
+func (v *Vault) GenerateIdentity(label string) (*IdentityInfo, error) {
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+	identity, err := crypto.GenerateIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return v.storeIdentity(label, identity)
+}
+
+func (v *Vault) ImportIdentity(label string, secret string) (*IdentityInfo, error) {
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+	identity, err := crypto.ParseIdentity(secret)
+	if err != nil {
+		return nil, err
+	}
+	recipient := identity.Recipient().String()
+	for _, stored := range v.index.Identities {
+		if stored.Recipient == recipient {
+			return nil, errors.New("identity already exists in this vault")
+		}
+	}
+	return v.storeIdentity(label, identity)
+}
+
+func (v *Vault) ExportIdentity(id string) (string, error) {
+	if !v.unlocked {
+		return "", errors.New("vault is locked")
+	}
+	stored := v.findIdentity(id)
+	if stored == nil {
+		return "", errors.New("identity not found")
+	}
+	identity, err := stored.identity()
+	if err != nil {
+		return "", err
+	}
+	return identity.String(), nil
+}
+
+func (v *Vault) RemoveIdentity(id string) error {
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	index := -1
+	for i := range v.index.Identities {
+		if v.index.Identities[i].ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return errors.New("identity not found")
+	}
+
+	previous := v.index.Identities
+	next := make([]StoredIdentity, 0, len(previous)-1)
+	next = append(next, previous[:index]...)
+	next = append(next, previous[index+1:]...)
+	v.index.Identities = next
+	if err := v.saveMetadata(); err != nil {
+		v.index.Identities = previous
+		return err
+	}
+	previous[index].wipe()
+	return nil
+}
+
+func (v *Vault) ExportEntryToRecipients(encryptedName string, recipients []string, destPath string) error {
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if len(recipients) == 0 {
+		return errors.New("at least one recipient required")
+	}
+	parsed := make([]*crypto.Recipient, 0, len(recipients))
+	for _, encoded := range recipients {
+		recipient, err := crypto.ParseRecipient(encoded)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, recipient)
+	}
+
+	entry := v.getIndexEntry(encryptedName)
+	if entry == nil {
+		return errors.New("file not found in vault index")
+	}
+	cipherData, ok := v.fileData[encryptedName]
+	if !ok {
+		return errors.New("vault data missing for requested file")
+	}
+	if !v.verifyBlobMAC(entry, cipherData) {
+		return errors.New("ciphertext integrity check failed")
+	}
+	source, err := v.entryCipher(entry)
+	if err != nil {
+		return err
+	}
+	var plaintext bytes.Buffer
+	if err := source.DecryptStreamVersion(bytes.NewReader(cipherData), &plaintext, entry.streamVersion(), v.blobAssociatedData(entry)); err != nil {
+		return err
+	}
+	defer crypto.WipeBytes(plaintext.Bytes())
+
+	return writeRecipientBundle(parsed, v.header.CascadeMode, entry.OriginalName, &plaintext, destPath)
+}
+
+func (v *Vault) ImportRecipientBundle(path string) (*FileEntry, error) {
+	if !v.unlocked {
+		return nil, errors.New("vault is locked")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := make([]*crypto.Identity, 0, len(v.index.Identities))
+	for i := range v.index.Identities {
+		identity, err := v.index.Identities[i].identity()
+		if err != nil {
+			continue
+		}
+		identities = append(identities, identity)
+	}
+
+	name, content, err := openRecipientBundle(data, identities)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(content)
+	return v.addEntry(bytes.NewReader(content), name, int64(len(content)))
+}
+
+func writeRecipientBundle(recipients []*crypto.Recipient, mode crypto.CascadeMode, name string, content io.Reader, destPath string) error {
+	if len(name) == 0 || len(name) > maxBundleNameLength {
+		return errors.New("invalid file name length")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return errors.New("destination file already exists")
+	}
+
+	fileKey, err := crypto.NewDataKey()
+	if err != nil {
+		return err
+	}
+	defer crypto.WipeBytes(fileKey)
+
+	header := recipientBundleHeader{
+		CascadeMode:   mode,
+		StreamVersion: crypto.CurrentStreamVersion,
+	}
+	for _, recipient := range recipients {
+		stanza, err := crypto.WrapForRecipient(recipient, fileKey)
+		if err != nil {
+			return err
+		}
+		header.Stanzas = append(header.Stanzas, *stanza)
+	}
+	prefix, err := encodeBundleHeader(&header)
+	if err != nil {
+		return err
+	}
+
+	var nameLength [2]byte
+	binary.BigEndian.PutUint16(nameLength[:], uint16(len(name)))
+	payload := io.MultiReader(bytes.NewReader(nameLength[:]), strings.NewReader(name), content)
+
+	bundleCipher, err := crypto.NewCascadeCipher(header.CascadeMode, fileKey)
+	if err != nil {
+		return err
+	}
+	out := bytes.NewBuffer(append([]byte(nil), prefix...))
+	if err := bundleCipher.EncryptStreamVersion(payload, out, header.StreamVersion, prefix); err != nil {
+		return err
+	}
+	return writeFileAtomic(destPath, out.Bytes(), 0o600)
+}
+
+func openRecipientBundle(data []byte, identities []*crypto.Identity) (string, []byte, error) {
+	header, prefix, err := decodeBundleHeader(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fileKey, err := openBundleStanzas(header.Stanzas, identities)
+	if err != nil {
+		return "", nil, err
+	}
+	defer crypto.WipeBytes(fileKey)
+
+	bundleCipher, err := crypto.NewCascadeCipher(header.CascadeMode, fileKey)
+	if err != nil {
+		return "", nil, err
+	}
+	var plaintext bytes.Buffer
+	if err := bundleCipher.DecryptStreamVersion(bytes.NewReader(data[len(prefix):]), &plaintext, header.StreamVersion, prefix); err != nil {
+		crypto.WipeBytes(plaintext.Bytes())
+		return "", nil, err
+	}
+
+	payload := plaintext.Bytes()
+	if len(payload) < 2 {
+		return "", nil, errors.New("corrupted recipient bundle")
+	}
+	nameLength := int(binary.BigEndian.Uint16(payload[:2]))
+	if len(payload) < 2+nameLength || nameLength == 0 {
+		crypto.WipeBytes(payload)
+		return "", nil, errors.New("corrupted recipient bundle")
+	}
+	name := filepath.Base(string(payload[2 : 2+nameLength]))
+	return name, payload[2+nameLength:], nil
+}
+
+func openBundleStanzas(stanzas []crypto.Stanza, identities []*crypto.Identity) ([]byte, error) {
+	for _, identity := range identities {
+		for i := range stanzas {
+			fileKey, err := crypto.UnwrapStanza(identity, &stanzas[i])
+			if err == nil {
+				return fileKey, nil
+			}
+		}
+	}
+	return nil, errors.New("no identity in this vault can open the bundle")
+}
+
+func (v *Vault) storeIdentity(label string, identity *crypto.Identity) (*IdentityInfo, error) {
+	label = strings.TrimSpace(label)
+	if len(label) == 0 {
+		return nil, errors.New("identity label cannot be empty")
+	}
+	if len(label) > maxIdentityLabel {
+		return nil, errors.New("identity label is too long")
+	}
+	id, err := randomHexID()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := StoredIdentity{
+		ID:        id,
+		Label:     label,
+		Recipient: identity.Recipient().String(),
+		Secret:    identity.Bytes(),
+		CreatedAt: time.Now(),
+	}
+	if err := stored.guard(); err != nil {
+		stored.wipe()
+		return nil, err
+	}
+	v.index.Identities = append(v.index.Identities, stored)
+	if err := v.saveMetadata(); err != nil {
+		v.index.Identities = v.index.Identities[:len(v.index.Identities)-1]
+		stored.wipe()
+		return nil, err
+	}
+	info := stored.info()
+	return &info, nil
+}
+
+func (v *Vault) findIdentity(id string) *StoredIdentity {
+	for i := range v.index.Identities {
+		if v.index.Identities[i].ID == id {
+			return &v.index.Identities[i]
+		}
+	}
+	return nil
+}
+
+func (s StoredIdentity) MarshalJSON() ([]byte, error) {
+	type storedIdentity StoredIdentity
+	plain := storedIdentity(s)
+	if s.secret != nil {
+		data, err := s.secret.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer s.secret.Seal()
+		plain.Secret = data
+	}
+	return json.Marshal(plain)
+}
+
+func (s *StoredIdentity) guard() error {
+	if s.secret != nil || len(s.Secret) == 0 {
+		return nil
+	}
+	secret, err := crypto.NewEnclave(len(s.Secret))
+	if err != nil {
+		return err
+	}
+	data, err := secret.Open()
+	if err != nil {
+		secret.Destroy()
+		return err
+	}
+	copy(data, s.Secret)
+	secret.Seal()
+	crypto.WipeBytes(s.Secret)
+	s.secret, s.Secret = secret, nil
+	return nil
+}
+
+func (s *StoredIdentity) identity() (*crypto.Identity, error) {
+	if s.secret == nil {
+		return crypto.ParseIdentityBytes(s.Secret)
+	}
+	data, err := s.secret.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer s.secret.Seal()
+	return crypto.ParseIdentityBytes(data)
+}
+
+func (s *StoredIdentity) wipe() {
+	if s.secret != nil {
+		s.secret.Destroy()
+		s.secret = nil
+	}
+	crypto.WipeBytes(s.Secret)
+}
+
+func (s *StoredIdentity) info() IdentityInfo {
+	return IdentityInfo{
+		ID:        s.ID,
+		Label:     s.Label,
+		Recipient: s.Recipient,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func encodeBundleHeader(header *recipientBundleHeader) ([]byte, error) {
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(recipientBundleMagic)
+	buf.WriteByte(recipientBundleVersion)
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(headerBytes)))
+	buf.Write(length[:])
+	buf.Write(headerBytes)
+	return buf.Bytes(), nil
+}
+
+func decodeBundleHeader(data []byte) (*recipientBundleHeader, []byte, error) {
+	fixed := len(recipientBundleMagic) + 1 + 4
+	if len(data) < fixed || string(data[:len(recipientBundleMagic)]) != recipientBundleMagic {
+		return nil, nil, errors.New("not a recipient bundle")
+	}
+	if data[len(recipientBundleMagic)] != recipientBundleVersion {
+		return nil, nil, errors.New("unsupported recipient bundle version")
+	}
+	length := binary.BigEndian.Uint32(data[len(recipientBundleMagic)+1 : fixed])
+	if length > maxBundleHeaderSize || uint64(len(data)) < uint64(fixed)+uint64(length) {
+		return nil, nil, errors.New("corrupted recipient bundle")
+	}
+	prefix := data[:fixed+int(length)]
+
+	var header recipientBundleHeader
+	if err := json.Unmarshal(prefix[fixed:], &header); err != nil {
+		return nil, nil, errors.New("corrupted recipient bundle")
+	}
+	if header.StreamVersion != crypto.CurrentStreamVersion {
+		return nil, nil, errors.New("unsupported recipient bundle stream version")
+	}
+	if len(header.Stanzas) == 0 {
+		return nil, nil, errors.New("recipient bundle has no stanzas")
+	}
+	return &header, prefix, nil
+}
@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"errors"
+	"os"
+
+	"micrypt/internal/crypto"
+)
+
+const maxRecoveryFileSize = 4096
+
+func OpenVaultFromRecoveryFile(path string, file string) (*Vault, error) {
+	data, err := readRecoveryFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(data)
+	return openVaultWithDerivedKeys(path, func(kdfMeta *crypto.KDFMetadata) (*crypto.KeySchedule, error) {
+		return crypto.DeriveKeyScheduleFromRecoveryFile(data, kdfMeta)
+	})
+}
+
+func RecoverVaultWithRecoveryFile(path string, file string, newPassword []byte, options *UnlockOptions) (*Vault, error) {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(newPassword, opts.Keyfiles)
+	if err := validateNewCredentials(newPassword, opts.Keyfiles); err != nil {
+		return nil, err
+	}
+
+	data, err := readRecoveryFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.WipeBytes(data)
+
+	v, err := openVaultWithDerivedKeys(path, func(kdfMeta *crypto.KDFMetadata) (*crypto.KeySchedule, error) {
+		return crypto.DeriveKeyScheduleFromRecoveryFile(data, kdfMeta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kdfMeta, err := crypto.ResetPasswordWithRecoveryFile(data, newPassword, opts.Keyfiles, opts.PIM, v.kdfMeta)
+	if err != nil {
+		v.Lock()
+		return nil, err
+	}
+	if err := v.replaceKDFMetadata(kdfMeta); err != nil {
+		v.Lock()
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *Vault) CreateRecoveryFile(password []byte, options *UnlockOptions, destPath string) error {
+	var opts UnlockOptions
+	if options != nil {
+		opts = *options
+	}
+	defer wipeCredentials(password, opts.Keyfiles)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if len(destPath) == 0 {
+		return errors.New("destination path cannot be empty")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return errors.New("destination file already exists")
+	}
+
+	vaultKey, err := crypto.UnwrapVaultKey(password, opts.Keyfiles, opts.PIM, v.kdfMeta)
+	if err != nil {
+		return err
+	}
+	defer vaultKey.Wipe()
+
+	slot, content, err := crypto.NewRecoveryFileSlot(vaultKey)
+	if err != nil {
+		return err
+	}
+	defer crypto.WipeBytes(content)
+	if err := writeFileAtomic(destPath, content, 0o600); err != nil {
+		return err
+	}
+
+	next := *v.kdfMeta
+	next.RecoveryFile = slot
+	if err := v.replaceKDFMetadata(&next); err != nil {
+		os.Remove(destPath)
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) RemoveRecoveryFile(password []byte, options *UnlockOptions) error {
+	if err := v.VerifyPassword(password, options); err != nil {
+		return err
+	}
+	if v.kdfMeta.RecoveryFile == nil {
+		return nil
+	}
+	next := *v.kdfMeta
+	next.RecoveryFile = nil
+	return v.replaceKDFMetadata(&next)
+}
+
+func (v *Vault) HasRecoveryFile() bool {
+	return v.unlocked && v.kdfMeta != nil && v.kdfMeta.RecoveryFile != nil
+}
+
+func readRecoveryFile(file string) ([]byte, error) {
+	if len(file) == 0 {
+		return nil, errors.New("recovery file path cannot be empty")
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() || info.Size() > maxRecoveryFileSize {
+		return nil, errors.New("not a micrypt recovery file")
+	}
+	return os.ReadFile(file)
+}
@@ -0,0 +1,131 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"micrypt/internal/bip39"
+	"micrypt/internal/crypto"
+	"micrypt/internal/qr"
+)
+
+var recoveryKitTemplate = template.Must(template.New("kit").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Micrypt recovery kit</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #111; max-width: 760px; margin: 32px auto; padding: 0 24px; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+p.note { font-size: 13px; color: #444; }
+.layout { display: flex; gap: 32px; align-items: flex-start; margin-top: 24px; }
+ol.words { columns: 3; column-gap: 24px; margin: 0; padding-left: 28px; font-family: "SFMono-Regular", Menlo, Consolas, monospace; font-size: 15px; line-height: 1.9; flex: 1; }
+.qr svg { width: 220px; height: 220px; }
+table { border-collapse: collapse; margin-top: 28px; font-size: 13px; }
+td { border: 1px solid #bbb; padding: 6px 10px; vertical-align: top; }
+td:first-child { font-weight: 600; white-space: nowrap; }
+.warning { margin-top: 24px; padding: 10px 14px; border: 2px solid #111; font-size: 13px; }
+@media print { body { margin: 0 auto; } }
+</style>
+</head>
+<body>
+<h1>Micrypt recovery kit</h1>
+<p class="note">Keep this sheet offline and out of sight. Anyone holding these words can open the vault.</p>
+<div class="layout">
+<ol class="words">{{range .Words}}<li>{{.}}</li>{{end}}</ol>
+<div class="qr">{{.QR}}</div>
+</div>
+{{if .Passphrase}}<div class="warning">This phrase was created with a recovery passphrase. The passphrase is not printed here and is required together with the words.</div>{{end}}
+<table>
+<tr><td>Vault ID</td><td>{{.VaultID}}</td></tr>
+<tr><td>Created</td><td>{{.CreatedAt}}</td></tr>
+<tr><td>Words</td><td>{{len .Words}} (BIP39, {{.Language}})</td></tr>
+<tr><td>Key derivation</td><td>{{.KDF}}</td></tr>
+<tr><td>Printed</td><td>{{.PrintedAt}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+type recoveryKitPage struct {
+	Words      []string
+	QR         template.HTML
+	Passphrase bool
+	Language   string
+	VaultID    string
+	CreatedAt  string
+	KDF        string
+	PrintedAt  string
+}
+
+func (v *Vault) WriteRecoveryKit(words []string, passphrase []byte, destPath string) error {
+	defer crypto.WipeBytes(passphrase)
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+	if len(destPath) == 0 {
+		return errors.New("destination path cannot be empty")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return errors.New("destination file already exists")
+	}
+	if v.index.RecoveryPassphrase && len(passphrase) == 0 {
+		return errors.New("recovery passphrase is required to verify the words")
+	}
+	mnemonic, err := bip39.RestoreFromMnemonicInLanguage(words, passphrase, v.MnemonicLanguage())
+	if err != nil {
+		return err
+	}
+	words = mnemonic.Words
+	err = v.verifyMnemonicSeed(mnemonic.Seed)
+	crypto.WipeBytes(mnemonic.Seed)
+	if err != nil {
+		return err
+	}
+
+	code, err := qr.Encode([]byte(strings.Join(words, " ")))
+	if err != nil {
+		return err
+	}
+	page := recoveryKitPage{
+		Words:      words,
+		QR:         template.HTML(code.SVG(4)),
+		Passphrase: v.index.RecoveryPassphrase,
+		Language:   languageName(v.MnemonicLanguage()),
+		VaultID:    v.header.VaultID,
+		CreatedAt:  v.header.CreatedAt.Format("2006-01-02 15:04 MST"),
+		KDF:        describeKDFParams(v.kdfMeta.Params),
+		PrintedAt:  time.Now().Format("2006-01-02 15:04 MST"),
+	}
+
+	var out bytes.Buffer
+	if err := recoveryKitTemplate.Execute(&out, page); err != nil {
+		return err
+	}
+	defer crypto.WipeBytes(out.Bytes())
+	return writeFileAtomic(destPath, out.Bytes(), 0o600)
+}
+
+func languageName(language string) string {
+	name := strings.ReplaceAll(language, "_", " ")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func describeKDFParams(params *crypto.KDFParams) string {
+	if params == nil {
+		return "unknown"
+	}
+	name := params.KDF
+	if len(name) == 0 {
+		name = crypto.DefaultKDF
+	}
+	if name == crypto.KDFScrypt {
+		return fmt.Sprintf("%s, N=2^%d, r=%d, p=%d", name, params.LogN, params.R, params.P)
+	}
+	return fmt.Sprintf("%s, %d passes, %d MiB, %d threads", name, params.Time, params.Memory/1024, params.Threads)
+}
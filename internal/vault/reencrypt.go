@@ -0,0 +1,284 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"micrypt/internal/crypto"
+)
+
+const (
+	reencryptDirSuffix   = ".reencrypt"
+	reencryptJournalName = "journal.json"
+)
+
+type ReencryptProgress func(done, total int)
+
+type reencryptJournal struct {
+	Mode crypto.CascadeMode `json:"mode"`
+	Done map[string][]byte  `json:"done"`
+	Keys map[string][]byte  `json:"keys,omitempty"`
+}
+
+type reencryptJournalFile struct {
+	Journal json.RawMessage `json:"journal"`
+	MAC     []byte          `json:"mac"`
+}
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.reader.Read(p)
+}
+
+func (v *Vault) ReencryptAll(ctx context.Context, newMode crypto.CascadeMode, progress ReencryptProgress) error {
+	if !v.unlocked {
+		return errors.New("vault is locked")
+	}
+
+	workDir := v.path + reencryptDirSuffix
+	if v.header.CascadeMode == newMode {
+		return os.RemoveAll(workDir)
+	}
+
+	err := v.withKeys(func(keys *crypto.KeySchedule) error {
+		_, err := crypto.NewCascadeCipher(newMode, keys.MasterKey)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	assigned, err := v.ensureBindingIDs()
+	if err != nil {
+		return err
+	}
+	if assigned {
+		if err := v.saveMetadata(); err != nil {
+			return err
+		}
+	}
+
+	journal, err := v.loadReencryptJournal(workDir)
+	if err != nil || journal.Mode != newMode {
+		if err := os.RemoveAll(workDir); err != nil {
+			return err
+		}
+		journal = &reencryptJournal{Mode: newMode, Done: make(map[string][]byte), Keys: make(map[string][]byte)}
+	}
+	if err := os.MkdirAll(workDir, 0o700); err != nil {
+		return err
+	}
+
+	total := len(v.index.Files)
+	converted := make(map[string][]byte, total)
+	defer func() {
+		for _, data := range converted {
+			crypto.WipeBytes(data)
+		}
+	}()
+
+	for i := range v.index.Files {
+		entry := &v.index.Files[i]
+		next := *entry
+		next.StreamVersion = crypto.CurrentStreamVersion
+		next.CipherMAC = journal.Done[entry.EncryptedName]
+		next.WrappedKey = journal.Keys[entry.EncryptedName]
+		blobPath := filepath.Join(workDir, entry.EncryptedName)
+
+		if len(next.CipherMAC) > 0 && len(next.WrappedKey) > 0 {
+			data, readErr := os.ReadFile(blobPath)
+			if readErr == nil && v.verifyBlobMAC(&next, data) {
+				converted[entry.EncryptedName] = data
+				if progress != nil {
+					progress(len(converted), total)
+				}
+				continue
+			}
+			delete(journal.Done, entry.EncryptedName)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		blobCipher, wrappedKey, err := v.sealEntryKey(&next, newMode)
+		if err != nil {
+			return err
+		}
+		next.WrappedKey = wrappedKey
+
+		data, err := v.reencryptBlob(ctx, blobCipher, entry, &next)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(blobPath, data, 0o600); err != nil {
+			crypto.WipeBytes(data)
+			return err
+		}
+		journal.Done[entry.EncryptedName] = v.blobMAC(&next, data)
+		journal.Keys[entry.EncryptedName] = next.WrappedKey
+		if err := v.saveReencryptJournal(workDir, journal); err != nil {
+			crypto.WipeBytes(data)
+			return err
+		}
+		converted[entry.EncryptedName] = data
+
+		if progress != nil {
+			progress(len(converted), total)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	previousMode := v.header.CascadeMode
+	previousData := v.fileData
+	previousMACs := make([][]byte, total)
+	previousVersions := make([]int, total)
+	previousKeys := make([][]byte, total)
+
+	nextData := make(map[string][]byte, total)
+	for i := range v.index.Files {
+		entry := &v.index.Files[i]
+		previousMACs[i] = entry.CipherMAC
+		previousVersions[i] = entry.StreamVersion
+		previousKeys[i] = entry.WrappedKey
+		entry.CipherMAC = journal.Done[entry.EncryptedName]
+		entry.StreamVersion = crypto.CurrentStreamVersion
+		entry.WrappedKey = journal.Keys[entry.EncryptedName]
+		nextData[entry.EncryptedName] = converted[entry.EncryptedName]
+	}
+
+	v.header.CascadeMode = newMode
+	v.header.ModifiedAt = time.Now()
+	v.fileData = nextData
+
+	if err := v.saveMetadata(); err != nil {
+		v.header.CascadeMode = previousMode
+		v.fileData = previousData
+		for i := range v.index.Files {
+			v.index.Files[i].CipherMAC = previousMACs[i]
+			v.index.Files[i].StreamVersion = previousVersions[i]
+			v.index.Files[i].WrappedKey = previousKeys[i]
+		}
+		return err
+	}
+
+	converted = nil
+	for _, data := range previousData {
+		crypto.WipeBytes(data)
+	}
+
+	return os.RemoveAll(workDir)
+}
+
+func (v *Vault) PendingReencryption() (crypto.CascadeMode, bool) {
+	if !v.unlocked {
+		return 0, false
+	}
+	journal, err := v.loadReencryptJournal(v.path + reencryptDirSuffix)
+	if err != nil {
+		return 0, false
+	}
+	return journal.Mode, journal.Mode != v.header.CascadeMode
+}
+
+func (v *Vault) reencryptBlob(ctx context.Context, target *crypto.CascadeCipher, entry *FileEntry, next *FileEntry) ([]byte, error) {
+	cipherData, ok := v.fileData[entry.EncryptedName]
+	if !ok {
+		return nil, errors.New("vault data missing for requested file")
+	}
+	if !v.verifyBlobMAC(entry, cipherData) {
+		return nil, errors.New("ciphertext integrity check failed")
+	}
+
+	source, err := v.entryCipher(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		reader := &contextReader{ctx: ctx, reader: bytes.NewReader(cipherData)}
+		pw.CloseWithError(source.DecryptStreamVersion(reader, pw, entry.streamVersion(), v.blobAssociatedData(entry)))
+	}()
+
+	var out bytes.Buffer
+	err = target.EncryptStreamVersion(pr, &out, next.StreamVersion, v.blobAssociatedData(next))
+	pr.CloseWithError(err)
+	if err != nil {
+		crypto.WipeBytes(out.Bytes())
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (v *Vault) loadReencryptJournal(workDir string) (*reencryptJournal, error) {
+	raw, err := os.ReadFile(filepath.Join(workDir, reencryptJournalName))
+	if err != nil {
+		return nil, err
+	}
+
+	var file reencryptJournalFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, errors.New("corrupted re-encryption journal")
+	}
+	verified := false
+	if err := v.withKeys(func(keys *crypto.KeySchedule) error {
+		verified = crypto.VerifyAuthMAC(keys.AuthKey, file.Journal, file.MAC)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, errors.New("re-encryption journal authentication failed")
+	}
+
+	var journal reencryptJournal
+	if err := json.Unmarshal(file.Journal, &journal); err != nil {
+		return nil, errors.New("corrupted re-encryption journal")
+	}
+	if journal.Done == nil {
+		journal.Done = make(map[string][]byte)
+	}
+	if journal.Keys == nil {
+		journal.Keys = make(map[string][]byte)
+	}
+	return &journal, nil
+}
+
+func (v *Vault) saveReencryptJournal(workDir string, journal *reencryptJournal) error {
+	journalBytes, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	var mac []byte
+	if err := v.withKeys(func(keys *crypto.KeySchedule) error {
+		mac = crypto.ComputeAuthMAC(keys.AuthKey, journalBytes)
+		return nil
+	}); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(reencryptJournalFile{
+		Journal: journalBytes,
+		MAC:     mac,
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(workDir, reencryptJournalName), raw, 0o600)
+}
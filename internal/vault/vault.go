@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -24,9 +25,12 @@ const (
 	containerVersion = 1
 
 	metadataMagic   = "MCMETA2"
-	metadataVersion = 2
+	metadataVersion = 3
 	maxMetadataSize = 1 << 20
 	maxIndexSize    = 1 << 24
+
+	blobBindingLabel = "micrypt/v2/blob"
+	bindingIDLength  = 16
 )
 
 type metadataFile struct {
@@ -36,6 +40,8 @@ type metadataFile struct {
 	AuthMAC           []byte          `json:"auth_mac"`
 	EncryptedHeader   []byte          `json:"encrypted_header"`
 	EncryptedMnemonic []byte          `json:"encrypted_mnemonic,omitempty"`
+	SaveSalt          []byte          `json:"save_salt,omitempty"`
+	DropboxRecipient  string          `json:"dropbox_recipient,omitempty"`
 }
 
 type VaultHeader struct {
@@ -44,37 +50,154 @@ type VaultHeader struct {
 	CascadeMode crypto.CascadeMode
 	CreatedAt   time.Time
 	ModifiedAt  time.Time
+	VaultID     string
+
+	MnemonicLanguage string `json:",omitempty"`
 }
 
 type FileEntry struct {
+	ID            string
 	EncryptedName string
 	OriginalName  string
 	Size          int64
 	EncryptedAt   time.Time
 	CipherMAC     []byte
+	StreamVersion int
+	WrappedKey    []byte
+}
+
+func (e *FileEntry) streamVersion() int {
+	if e.StreamVersion == 0 {
+		return crypto.StreamVersion1
+	}
+	return e.StreamVersion
+}
+
+func (v *Vault) blobAssociatedData(entry *FileEntry) []byte {
+	version := entry.streamVersion()
+	if version < crypto.StreamVersion3 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString(blobBindingLabel)
+	for _, field := range []string{v.header.VaultID, entry.ID} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+		buf.Write(length[:])
+		buf.WriteString(field)
+	}
+	buf.WriteByte(byte(version))
+	return buf.Bytes()
+}
+
+func (v *Vault) blobMAC(entry *FileEntry, cipherData []byte) []byte {
+	var mac []byte
+	v.withKeys(func(keys *crypto.KeySchedule) error {
+		mac = v.blobMACWithKey(keys.AuthKey, entry, cipherData)
+		return nil
+	})
+	return mac
+}
+
+func (v *Vault) blobMACWithKey(authKey []byte, entry *FileEntry, cipherData []byte) []byte {
+	ad := v.blobAssociatedData(entry)
+	if ad == nil {
+		return crypto.ComputeAuthMAC(authKey, cipherData)
+	}
+	mac, _ := crypto.ComputeAuthMACStream(authKey, io.MultiReader(bytes.NewReader(ad), bytes.NewReader(cipherData)))
+	return mac
+}
+
+func (v *Vault) verifyBlobMAC(entry *FileEntry, cipherData []byte) bool {
+	if len(entry.CipherMAC) == 0 {
+		return false
+	}
+	mac := v.blobMAC(entry, cipherData)
+	defer crypto.WipeBytes(mac)
+	return subtle.ConstantTimeCompare(mac, entry.CipherMAC) == 1
+}
+
+func (v *Vault) ensureBindingIDs() (bool, error) {
+	changed := false
+	if len(v.header.VaultID) == 0 {
+		id, err := randomHexID()
+		if err != nil {
+			return false, err
+		}
+		v.header.VaultID = id
+		changed = true
+	}
+	for i := range v.index.Files {
+		if len(v.index.Files[i].ID) > 0 {
+			continue
+		}
+		id, err := randomHexID()
+		if err != nil {
+			return false, err
+		}
+		v.index.Files[i].ID = id
+		changed = true
+	}
+	return changed, nil
+}
+
+func randomHexID() (string, error) {
+	buf := make([]byte, bindingIDLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 type VaultIndex struct {
-	Files []FileEntry
+	Files              []FileEntry
+	Identities         []StoredIdentity
+	Dropbox            *StoredIdentity
+	DropboxIngested    []string `json:",omitempty"`
+	RecoveryPassphrase bool     `json:",omitempty"`
+	RecoveryWords      int      `json:",omitempty"`
+}
+
+func (idx *VaultIndex) guardSecrets() error {
+	for i := range idx.Identities {
+		if err := idx.Identities[i].guard(); err != nil {
+			return err
+		}
+	}
+	if idx.Dropbox != nil {
+		return idx.Dropbox.guard()
+	}
+	return nil
 }
 
 type Vault struct {
 	path           string
 	header         *VaultHeader
-	cipher         *crypto.CascadeCipher
-	metadataCipher *crypto.Cipher
-	authKey        []byte
+	keys           *crypto.KeySchedule
 	index          *VaultIndex
 	unlocked       bool
 	kdfMeta        *crypto.KDFMetadata
 	fileData       map[string][]byte
 	storedMnemonic []string
+	report         UnlockReport
+}
+
+type UnlockReport struct {
+	KDFUpgradeError error
+	DropboxIngested int
+	DropboxFailed   int
+	DropboxError    error
 }
 
 type VaultCreationOptions struct {
-	Keyfiles [][]byte
-	PIM      uint32
-	Entropy  []byte
+	Keyfiles           [][]byte
+	PIM                uint32
+	Entropy            []byte
+	KDFParams          *crypto.KDFParams
+	MnemonicWords      int
+	RecoveryPassphrase []byte
+	OmitStoredMnemonic bool
+	MnemonicLanguage   string
 }
 
 type UnlockOptions struct {
@@ -86,6 +209,12 @@ func OpenVaultFromMnemonicSeed(path string, mnemonicSeed []byte) (*Vault, error)
 	if len(mnemonicSeed) == 0 {
 		return nil, errors.New("mnemonic seed required")
 	}
+	return openVaultWithDerivedKeys(path, func(kdfMeta *crypto.KDFMetadata) (*crypto.KeySchedule, error) {
+		return crypto.DeriveKeyScheduleFromSeed(mnemonicSeed, kdfMeta)
+	})
+}
+
+func openVaultWithDerivedKeys(path string, derive func(*crypto.KDFMetadata) (*crypto.KeySchedule, error)) (*Vault, error) {
 	metaFile, encryptedIndex, blobs, err := loadContainerFile(path)
 	if err != nil {
 		return nil, err
@@ -96,12 +225,12 @@ func OpenVaultFromMnemonicSeed(path string, mnemonicSeed []byte) (*Vault, error)
 		return nil, errors.New("corrupted vault authentication data")
 	}
 
-	keySchedule, err := crypto.DeriveKeyScheduleFromSeed(mnemonicSeed, &kdfMeta)
+	keySchedule, err := derive(&kdfMeta)
 	if err != nil {
 		return nil, err
 	}
 
-	if !crypto.VerifyAuthMAC(keySchedule.AuthKey, metaFile.Auth, metaFile.AuthMAC) {
+	if !crypto.VerifyAuthMAC(keySchedule.AuthKey, authMACData(metaFile.Auth, metaFile.DropboxRecipient), metaFile.AuthMAC) {
 		keySchedule.Wipe()
 		return nil, errors.New("vault metadata authentication failed")
 	}
@@ -112,22 +241,19 @@ func OpenVaultFromMnemonicSeed(path string, mnemonicSeed []byte) (*Vault, error)
 		return nil, err
 	}
 
-	metaVersion := metaFile.Version
-	if metaVersion == 0 {
-		metaVersion = 1
-	}
-	if metaVersion != 1 && metaVersion != metadataVersion {
+	sectionCipher, err := metadataSectionCipher(metaFile, keySchedule.MetadataKey, metadataCipher)
+	if err != nil {
 		keySchedule.Wipe()
-		return nil, errors.New("unsupported vault metadata version")
+		return nil, err
 	}
 
-	storedMnemonic, err := decryptStoredMnemonic(metaFile, metadataCipher)
+	storedMnemonic, err := decryptStoredMnemonic(metaFile, sectionCipher)
 	if err != nil {
 		keySchedule.Wipe()
 		return nil, err
 	}
 
-	headerBytes, err := metadataCipher.Decrypt(metaFile.EncryptedHeader)
+	headerBytes, err := sectionCipher.Decrypt(metaFile.EncryptedHeader)
 	if err != nil {
 		keySchedule.Wipe()
 		return nil, err
@@ -141,27 +267,22 @@ func OpenVaultFromMnemonicSeed(path string, mnemonicSeed []byte) (*Vault, error)
 	}
 	crypto.WipeBytes(headerBytes)
 
-	cascadeCipher, err := crypto.NewCascadeCipher(header.CascadeMode, keySchedule.MasterKey)
-	if err != nil {
+	if _, err := crypto.NewCascadeCipher(header.CascadeMode, keySchedule.MasterKey); err != nil {
 		keySchedule.Wipe()
 		return nil, err
 	}
 
-	authKey := append([]byte(nil), keySchedule.AuthKey...)
-
 	vault := &Vault{
-		path:           path,
-		header:         &header,
-		cipher:         cascadeCipher,
-		metadataCipher: metadataCipher,
-		authKey:        authKey,
-		unlocked:       true,
-		kdfMeta:        &kdfMeta,
-		index:          &VaultIndex{Files: []FileEntry{}},
-		fileData:       make(map[string][]byte),
-	}
-
-	decryptedIndex, err := metadataCipher.Decrypt(encryptedIndex)
+		path:     path,
+		header:   &header,
+		keys:     keySchedule,
+		unlocked: true,
+		kdfMeta:  &kdfMeta,
+		index:    &VaultIndex{Files: []FileEntry{}},
+		fileData: make(map[string][]byte),
+	}
+
+	decryptedIndex, err := sectionCipher.Decrypt(encryptedIndex)
 	if err != nil {
 		keySchedule.Wipe()
 		return nil, err
@@ -185,29 +306,32 @@ func OpenVaultFromMnemonicSeed(path string, mnemonicSeed []byte) (*Vault, error)
 
 	for i, entry := range index.Files {
 		cipherCopy := append([]byte(nil), blobs[i]...)
-		mac := crypto.ComputeAuthMAC(vault.authKey, cipherCopy)
-		if len(entry.CipherMAC) == 0 || subtle.ConstantTimeCompare(mac, entry.CipherMAC) != 1 {
-			crypto.WipeBytes(mac)
+		if !vault.verifyBlobMAC(&entry, cipherCopy) {
 			keySchedule.Wipe()
 			return nil, errors.New("ciphertext integrity verification failed")
 		}
-		crypto.WipeBytes(mac)
 		vault.fileData[entry.EncryptedName] = cipherCopy
 	}
-	keySchedule.Wipe()
+	if err := index.guardSecrets(); err != nil {
+		vault.Lock()
+		return nil, err
+	}
+	keySchedule.Seal()
+
+	vault.ingestDropboxOnUnlock()
 
 	return vault, nil
 }
 
-func CreateVault(path string, password string, cascadeMode crypto.CascadeMode) (*Vault, *bip39.Mnemonic, error) {
+func CreateVault(path string, password []byte, cascadeMode crypto.CascadeMode) (*Vault, *bip39.Mnemonic, error) {
 	return CreateVaultWithEntropyOptions(path, password, cascadeMode, nil, nil)
 }
 
-func CreateVaultWithEntropy(path string, password string, cascadeMode crypto.CascadeMode, entropySeed []byte) (*Vault, *bip39.Mnemonic, error) {
+func CreateVaultWithEntropy(path string, password []byte, cascadeMode crypto.CascadeMode, entropySeed []byte) (*Vault, *bip39.Mnemonic, error) {
 	return CreateVaultWithEntropyOptions(path, password, cascadeMode, entropySeed, nil)
 }
 
-func CreateVaultWithEntropyOptions(path string, password string, cascadeMode crypto.CascadeMode, entropySeed []byte, options *VaultCreationOptions) (*Vault, *bip39.Mnemonic, error) {
+func CreateVaultWithEntropyOptions(path string, password []byte, cascadeMode crypto.CascadeMode, entropySeed []byte, options *VaultCreationOptions) (*Vault, *bip39.Mnemonic, error) {
 	var opts VaultCreationOptions
 	if options != nil {
 		opts = *options
@@ -216,20 +340,15 @@ func CreateVaultWithEntropyOptions(path string, password string, cascadeMode cry
 		opts.Entropy = entropySeed
 	}
 	defer func() {
-		for _, kf := range opts.Keyfiles {
-			crypto.WipeBytes(kf)
-		}
+		wipeCredentials(password, opts.Keyfiles)
+		crypto.WipeBytes(opts.RecoveryPassphrase)
 		if options != nil && len(options.Entropy) > 0 {
 			crypto.WipeBytes(options.Entropy)
 			options.Entropy = nil
 		}
 	}()
-	if len(password) == 0 {
-		if len(opts.Keyfiles) == 0 {
-			return nil, nil, errors.New("password must be at least 8 characters or keyfiles required")
-		}
-	} else if len(password) < 8 {
-		return nil, nil, errors.New("password must be at least 8 characters")
+	if err := validateNewCredentials(password, opts.Keyfiles); err != nil {
+		return nil, nil, err
 	}
 	containerPath, err := resolveCreatePath(path)
 	if err != nil {
@@ -245,8 +364,20 @@ func CreateVaultWithEntropyOptions(path string, password string, cascadeMode cry
 	crypto.WipeBytes(saltInput)
 
 	kdfParams := crypto.NewKDFParams(passwordSalt)
+	if opts.KDFParams != nil {
+		custom := *opts.KDFParams
+		custom.Salt = passwordSalt
+		kdfParams = &custom
+	}
 
-	mnemonic, err := bip39.GenerateMnemonic(bip39.Mnemonic12Words)
+	mnemonicBits, err := bip39.BitsForWordCount(opts.MnemonicWords)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(opts.MnemonicLanguage) > 0 && !bip39.ValidLanguage(opts.MnemonicLanguage) {
+		return nil, nil, fmt.Errorf("unsupported recovery phrase language %q", opts.MnemonicLanguage)
+	}
+	mnemonic, err := bip39.GenerateMnemonicInLanguage(mnemonicBits, opts.RecoveryPassphrase, opts.MnemonicLanguage)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -256,67 +387,68 @@ func CreateVaultWithEntropyOptions(path string, password string, cascadeMode cry
 		return nil, nil, err
 	}
 
-	cascadeCipher, err := crypto.NewCascadeCipher(cascadeMode, keySchedule.MasterKey)
-	if err != nil {
+	if _, err := crypto.NewCascadeCipher(cascadeMode, keySchedule.MasterKey); err != nil {
 		keySchedule.Wipe()
 		return nil, nil, err
 	}
 
-	metadataCipher, err := crypto.NewCipher(crypto.AES256GCM, keySchedule.MetadataKey)
+	vaultID, err := randomHexID()
 	if err != nil {
 		keySchedule.Wipe()
 		return nil, nil, err
 	}
 
-	authKey := append([]byte(nil), keySchedule.AuthKey...)
-
 	header := &VaultHeader{
 		Magic:       HeaderMagic,
 		Version:     VaultVersion,
 		CascadeMode: cascadeMode,
 		CreatedAt:   time.Now(),
 		ModifiedAt:  time.Now(),
+		VaultID:     vaultID,
+
+		MnemonicLanguage: mnemonic.Language,
+	}
+
+	index := &VaultIndex{
+		Files:              []FileEntry{},
+		RecoveryPassphrase: len(opts.RecoveryPassphrase) > 0,
+		RecoveryWords:      len(mnemonic.Words),
 	}
 
 	vault := &Vault{
-		path:           containerPath,
-		header:         header,
-		cipher:         cascadeCipher,
-		metadataCipher: metadataCipher,
-		authKey:        authKey,
-		index:          &VaultIndex{Files: []FileEntry{}},
-		unlocked:       true,
-		kdfMeta:        kdfMeta,
-		fileData:       make(map[string][]byte),
+		path:     containerPath,
+		header:   header,
+		keys:     keySchedule,
+		index:    index,
+		unlocked: true,
+		kdfMeta:  kdfMeta,
+		fileData: make(map[string][]byte),
 	}
 
-	vault.SetStoredMnemonic(mnemonic.Words)
+	if !opts.OmitStoredMnemonic {
+		vault.SetStoredMnemonic(mnemonic.Words)
+	}
 
 	if err := vault.saveMetadata(); err != nil {
-		keySchedule.Wipe()
 		vault.Lock()
 		return nil, nil, err
 	}
 
-	keySchedule.Wipe()
+	keySchedule.Seal()
 
 	return vault, mnemonic, nil
 }
 
-func OpenVault(path string, password string) (*Vault, error) {
+func OpenVault(path string, password []byte) (*Vault, error) {
 	return OpenVaultWithOptions(path, password, nil)
 }
 
-func OpenVaultWithOptions(path string, password string, options *UnlockOptions) (*Vault, error) {
+func OpenVaultWithOptions(path string, password []byte, options *UnlockOptions) (*Vault, error) {
 	var opts UnlockOptions
 	if options != nil {
 		opts = *options
 	}
-	defer func() {
-		for _, kf := range opts.Keyfiles {
-			crypto.WipeBytes(kf)
-		}
-	}()
+	defer wipeCredentials(password, opts.Keyfiles)
 	if len(password) == 0 && len(opts.Keyfiles) == 0 {
 		return nil, errors.New("password or keyfile required")
 	}
@@ -324,107 +456,25 @@ func OpenVaultWithOptions(path string, password string, options *UnlockOptions)
 		return nil, errors.New("vault path cannot be empty")
 	}
 
-	metaFile, encryptedIndex, blobs, err := loadContainerFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var kdfMeta crypto.KDFMetadata
-	if err := json.Unmarshal(metaFile.Auth, &kdfMeta); err != nil {
-		return nil, errors.New("corrupted vault authentication data")
-	}
-
-	keySchedule, err := crypto.DeriveKeyScheduleFromPassword(password, opts.Keyfiles, opts.PIM, &kdfMeta)
-	if err != nil {
-		return nil, err
-	}
-
-	if !crypto.VerifyAuthMAC(keySchedule.AuthKey, metaFile.Auth, metaFile.AuthMAC) {
-		keySchedule.Wipe()
-		return nil, errors.New("vault metadata authentication failed")
-	}
-
-	metadataCipher, err := crypto.NewCipher(crypto.AES256GCM, keySchedule.MetadataKey)
-	if err != nil {
-		keySchedule.Wipe()
-		return nil, err
-	}
-
-	headerBytes, err := metadataCipher.Decrypt(metaFile.EncryptedHeader)
-	if err != nil {
-		keySchedule.Wipe()
-		return nil, err
-	}
-
-	var header VaultHeader
-	if err := json.Unmarshal(headerBytes, &header); err != nil {
-		crypto.WipeBytes(headerBytes)
-		keySchedule.Wipe()
-		return nil, err
-	}
-	crypto.WipeBytes(headerBytes)
-
-	cascadeCipher, err := crypto.NewCascadeCipher(header.CascadeMode, keySchedule.MasterKey)
-	if err != nil {
-		keySchedule.Wipe()
-		return nil, err
-	}
-
-	authKey := append([]byte(nil), keySchedule.AuthKey...)
-
-	vault := &Vault{
-		path:           path,
-		header:         &header,
-		cipher:         cascadeCipher,
-		metadataCipher: metadataCipher,
-		authKey:        authKey,
-		unlocked:       true,
-		kdfMeta:        &kdfMeta,
-		index:          &VaultIndex{Files: []FileEntry{}},
-		fileData:       make(map[string][]byte),
-	}
-
-	decryptedIndex, err := metadataCipher.Decrypt(encryptedIndex)
-	if err != nil {
-		keySchedule.Wipe()
-		return nil, err
-	}
-
-	var index VaultIndex
-	if err := json.Unmarshal(decryptedIndex, &index); err != nil {
-		keySchedule.Wipe()
-		crypto.WipeBytes(decryptedIndex)
-		return nil, err
-	}
-
-	crypto.WipeBytes(decryptedIndex)
-
-	if len(blobs) != len(index.Files) {
-		keySchedule.Wipe()
-		return nil, errors.New("vault container is inconsistent")
-	}
-
-	vault.index = &index
-	storedMnemonic, err := decryptStoredMnemonic(metaFile, metadataCipher)
+	var vaultKey *crypto.VaultKey
+	defer func() {
+		if vaultKey != nil {
+			vaultKey.Wipe()
+		}
+	}()
+	vault, err := openVaultWithDerivedKeys(path, func(kdfMeta *crypto.KDFMetadata) (*crypto.KeySchedule, error) {
+		key, err := crypto.UnwrapVaultKey(password, opts.Keyfiles, opts.PIM, kdfMeta)
+		if err != nil {
+			return nil, err
+		}
+		vaultKey = key
+		return crypto.DeriveKeyScheduleFromVaultKey(key, kdfMeta)
+	})
 	if err != nil {
-		keySchedule.Wipe()
 		return nil, err
 	}
-	vault.SetStoredMnemonic(storedMnemonic)
 
-	for i, entry := range index.Files {
-		cipherCopy := append([]byte(nil), blobs[i]...)
-		mac := crypto.ComputeAuthMAC(vault.authKey, cipherCopy)
-		if len(entry.CipherMAC) == 0 || subtle.ConstantTimeCompare(mac, entry.CipherMAC) != 1 {
-			crypto.WipeBytes(mac)
-			keySchedule.Wipe()
-			return nil, errors.New("ciphertext integrity verification failed")
-		}
-		crypto.WipeBytes(mac)
-		vault.fileData[entry.EncryptedName] = cipherCopy
-	}
-
-	keySchedule.Wipe()
+	vault.report.KDFUpgradeError = vault.upgradeKDF(vaultKey, password, &opts)
 
 	return vault, nil
 }
@@ -455,42 +505,81 @@ func (v *Vault) EncryptFile(sourcePath string) (*FileEntry, error) {
 		return nil, err
 	}
 
-	encryptedName, err := v.generateEncryptedFilename()
+	return v.addEntry(sourceFile, filepath.Base(sourcePath), stat.Size())
+}
+
+func (v *Vault) addEntry(source io.Reader, originalName string, size int64) (*FileEntry, error) {
+	entry, cipherData, err := v.sealEntry(source, originalName, size)
 	if err != nil {
 		return nil, err
 	}
 
-	var cipherBuf bytes.Buffer
-	if err := v.cipher.EncryptStream(sourceFile, &cipherBuf); err != nil {
+	v.index.Files = append(v.index.Files, *entry)
+	v.fileData[entry.EncryptedName] = cipherData
+	v.header.ModifiedAt = time.Now()
+
+	if err := v.saveMetadata(); err != nil {
+		delete(v.fileData, entry.EncryptedName)
+		v.index.Files = v.index.Files[:len(v.index.Files)-1]
+		crypto.WipeBytes(cipherData)
 		return nil, err
 	}
-	cipherData := append([]byte(nil), cipherBuf.Bytes()...)
 
-	mac := crypto.ComputeAuthMAC(v.authKey, cipherData)
-	macCopy := append([]byte(nil), mac...)
-	crypto.WipeBytes(mac)
+	return entry, nil
+}
+
+func (v *Vault) sealEntry(source io.Reader, originalName string, size int64) (*FileEntry, []byte, error) {
+	encryptedName, err := v.generateEncryptedFilename()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := v.ensureBindingIDs(); err != nil {
+		return nil, nil, err
+	}
+	entryID, err := randomHexID()
+	if err != nil {
+		return nil, nil, err
+	}
 
 	entry := &FileEntry{
+		ID:            entryID,
 		EncryptedName: encryptedName,
-		OriginalName:  filepath.Base(sourcePath),
-		Size:          stat.Size(),
+		OriginalName:  originalName,
+		Size:          size,
 		EncryptedAt:   time.Now(),
-		CipherMAC:     macCopy,
+		StreamVersion: crypto.CurrentStreamVersion,
 	}
 
-	v.index.Files = append(v.index.Files, *entry)
-	v.fileData[encryptedName] = cipherData
-	v.header.ModifiedAt = time.Now()
+	blobCipher, wrappedKey, err := v.sealEntryKey(entry, v.header.CascadeMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	entry.WrappedKey = wrappedKey
 
-	if err := v.saveMetadata(); err != nil {
-		delete(v.fileData, encryptedName)
-		v.index.Files = v.index.Files[:len(v.index.Files)-1]
-		crypto.WipeBytes(cipherData)
-		crypto.WipeBytes(macCopy)
-		return nil, err
+	counter := &countingReader{reader: source}
+	var cipherBuf bytes.Buffer
+	if err := blobCipher.EncryptStreamVersion(counter, &cipherBuf, entry.StreamVersion, v.blobAssociatedData(entry)); err != nil {
+		return nil, nil, err
+	}
+	if size < 0 {
+		entry.Size = counter.n
 	}
+	cipherData := append([]byte(nil), cipherBuf.Bytes()...)
 
-	return entry, nil
+	entry.CipherMAC = v.blobMAC(entry, cipherData)
+	return entry, cipherData, nil
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.n += int64(n)
+	return n, err
 }
 
 func (v *Vault) DecryptFile(encryptedName string, destPath string) error {
@@ -517,24 +606,26 @@ func (v *Vault) DecryptFile(encryptedName string, destPath string) error {
 		return errors.New("vault data missing for requested file")
 	}
 
-	mac := crypto.ComputeAuthMAC(v.authKey, cipherData)
-	if subtle.ConstantTimeCompare(mac, entry.CipherMAC) != 1 {
-		crypto.WipeBytes(mac)
+	if !v.verifyBlobMAC(entry, cipherData) {
 		return errors.New("ciphertext integrity check failed")
 	}
-	crypto.WipeBytes(mac)
 
 	if _, err := os.Stat(destPath); err == nil {
 		return errors.New("destination file already exists")
 	}
 
+	blobCipher, err := v.entryCipher(entry)
+	if err != nil {
+		return err
+	}
+
 	destFile, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
 	if err != nil {
 		return err
 	}
 
 	cipherReader := bytes.NewReader(cipherData)
-	if err := v.cipher.DecryptStream(cipherReader, destFile); err != nil {
+	if err := blobCipher.DecryptStreamVersion(cipherReader, destFile, entry.streamVersion(), v.blobAssociatedData(entry)); err != nil {
 		destFile.Close()
 		os.Remove(destPath)
 		return err
@@ -591,17 +682,21 @@ func (v *Vault) DeleteFile(encryptedName string) error {
 
 func (v *Vault) Lock() {
 	v.unlocked = false
-	if v.cipher != nil {
-		v.cipher = nil
-	}
-	if v.metadataCipher != nil {
-		v.metadataCipher = nil
-	}
-	if v.authKey != nil {
-		crypto.WipeBytes(v.authKey)
-		v.authKey = nil
+	if v.keys != nil {
+		v.keys.Wipe()
+		v.keys = nil
 	}
 	v.SetStoredMnemonic(nil)
+	if v.index != nil {
+		for i := range v.index.Identities {
+			v.index.Identities[i].wipe()
+		}
+		v.index.Identities = nil
+		if v.index.Dropbox != nil {
+			v.index.Dropbox.wipe()
+			v.index.Dropbox = nil
+		}
+	}
 	for k, data := range v.fileData {
 		crypto.WipeBytes(data)
 		delete(v.fileData, k)
@@ -609,7 +704,7 @@ func (v *Vault) Lock() {
 	v.fileData = nil
 }
 
-func (v *Vault) VerifyPassword(password string, options *UnlockOptions) error {
+func (v *Vault) VerifyPassword(password []byte, options *UnlockOptions) error {
 	if !v.unlocked {
 		return errors.New("vault is locked")
 	}
@@ -617,14 +712,10 @@ func (v *Vault) VerifyPassword(password string, options *UnlockOptions) error {
 	if options != nil {
 		opts = *options
 	}
+	defer wipeCredentials(password, opts.Keyfiles)
 	if len(password) == 0 && len(opts.Keyfiles) == 0 {
 		return errors.New("password or keyfile required")
 	}
-	defer func() {
-		for _, kf := range opts.Keyfiles {
-			crypto.WipeBytes(kf)
-		}
-	}()
 
 	keySchedule, err := crypto.DeriveKeyScheduleFromPassword(password, opts.Keyfiles, opts.PIM, v.kdfMeta)
 	if err != nil {
@@ -638,6 +729,10 @@ func (v *Vault) IsUnlocked() bool {
 	return v.unlocked
 }
 
+func (v *Vault) UnlockReport() UnlockReport {
+	return v.report
+}
+
 func (v *Vault) GetPath() string {
 	return v.path
 }
@@ -665,29 +760,45 @@ func (v *Vault) StoredMnemonic() []string {
 	return out
 }
 
-func (v *Vault) UpdateStoredMnemonic(words []string) error {
-	if v == nil {
-		return errors.New("vault is nil")
-	}
-	v.SetStoredMnemonic(words)
-	return v.saveMetadata()
-}
-
 func (v *Vault) saveMetadata() error {
 	if !v.unlocked {
 		return errors.New("vault is locked")
 	}
-	if v.metadataCipher == nil {
+	if v.keys == nil {
 		return errors.New("metadata cipher is not initialized")
 	}
 
+	saveSalt, err := crypto.GenerateSalt()
+	if err != nil {
+		return err
+	}
+	authBytes, err := json.Marshal(v.kdfMeta)
+	if err != nil {
+		return err
+	}
+
+	var saveCipher *crypto.Cipher
+	var mac []byte
+	dropboxRecipient := ""
+	if v.index.Dropbox != nil {
+		dropboxRecipient = v.index.Dropbox.Recipient
+	}
+	err = v.withKeys(func(keys *crypto.KeySchedule) error {
+		saveCipher, err = crypto.NewMetadataSaveCipher(keys.MetadataKey, saveSalt)
+		mac = crypto.ComputeAuthMAC(keys.AuthKey, authMACData(authBytes, dropboxRecipient))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
 	var encryptedMnemonic []byte
 	if len(v.storedMnemonic) > 0 {
 		mnemonicData, err := json.Marshal(v.storedMnemonic)
 		if err != nil {
 			return err
 		}
-		cipherMnemonic, err := v.metadataCipher.Encrypt(mnemonicData)
+		cipherMnemonic, err := saveCipher.Encrypt(mnemonicData)
 		crypto.WipeBytes(mnemonicData)
 		if err != nil {
 			return err
@@ -695,19 +806,12 @@ func (v *Vault) saveMetadata() error {
 		encryptedMnemonic = cipherMnemonic
 	}
 
-	authBytes, err := json.Marshal(v.kdfMeta)
-	if err != nil {
-		return err
-	}
-
-	mac := crypto.ComputeAuthMAC(v.authKey, authBytes)
-
 	headerBytes, err := json.Marshal(v.header)
 	if err != nil {
 		return err
 	}
 
-	encryptedHeader, err := v.metadataCipher.Encrypt(headerBytes)
+	encryptedHeader, err := saveCipher.Encrypt(headerBytes)
 	if err != nil {
 		return err
 	}
@@ -719,6 +823,8 @@ func (v *Vault) saveMetadata() error {
 		AuthMAC:           mac,
 		EncryptedHeader:   encryptedHeader,
 		EncryptedMnemonic: encryptedMnemonic,
+		SaveSalt:          saveSalt,
+		DropboxRecipient:  dropboxRecipient,
 	}
 
 	metaBytes, err := json.Marshal(meta)
@@ -734,7 +840,7 @@ func (v *Vault) saveMetadata() error {
 		return err
 	}
 
-	encryptedIndex, err := v.metadataCipher.Encrypt(indexData)
+	encryptedIndex, err := saveCipher.Encrypt(indexData)
 	if err != nil {
 		crypto.WipeBytes(authBytes)
 		crypto.WipeBytes(headerBytes)
@@ -815,7 +921,15 @@ func (v *Vault) generateEncryptedFilename() (string, error) {
 
 	combined := append(buf.Bytes(), randomBytes...)
 
-	nameBytes, err := v.metadataCipher.Encrypt(combined)
+	var nameBytes []byte
+	err := v.withKeys(func(keys *crypto.KeySchedule) error {
+		nameCipher, err := crypto.NewCipher(crypto.AES256GCM, keys.MetadataKey)
+		if err != nil {
+			return err
+		}
+		nameBytes, err = nameCipher.Encrypt(combined)
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -934,42 +1048,79 @@ func ensureVaultFile(path string) error {
 	return nil
 }
 
-func loadContainerFile(path string) (*metadataFile, []byte, [][]byte, error) {
+func authMACData(auth []byte, dropboxRecipient string) []byte {
+	if len(dropboxRecipient) == 0 {
+		return auth
+	}
+	data := make([]byte, 0, len(auth)+1+len(dropboxRecipient))
+	data = append(data, auth...)
+	data = append(data, 0)
+	return append(data, dropboxRecipient...)
+}
+
+func loadContainerMetadata(path string) (*metadataFile, error) {
 	if err := ensureVaultFile(path); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 	defer file.Close()
 
+	return readContainerMetadata(file)
+}
+
+func readContainerMetadata(file io.Reader) (*metadataFile, error) {
 	magic := make([]byte, len(containerMagic))
 	if _, err := io.ReadFull(file, magic); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 	if string(magic) != containerMagic {
-		return nil, nil, nil, errors.New("invalid vault container magic")
+		return nil, errors.New("invalid vault container magic")
 	}
 
 	var version uint32
 	if err := binary.Read(file, binary.BigEndian, &version); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 	if version != containerVersion {
-		return nil, nil, nil, errors.New("unsupported vault container version")
+		return nil, errors.New("unsupported vault container version")
 	}
 
 	var metaLen uint32
 	if err := binary.Read(file, binary.BigEndian, &metaLen); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 	if metaLen == 0 || metaLen > maxMetadataSize {
-		return nil, nil, nil, errors.New("vault metadata section too large")
+		return nil, errors.New("vault metadata section too large")
 	}
 	metaBytes := make([]byte, metaLen)
 	if _, err := io.ReadFull(file, metaBytes); err != nil {
+		return nil, err
+	}
+
+	var meta metadataFile
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return nil, errors.New("corrupted vault metadata")
+	}
+	return &meta, nil
+}
+
+func loadContainerFile(path string) (*metadataFile, []byte, [][]byte, error) {
+	if err := ensureVaultFile(path); err != nil {
+		return nil, nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+
+	meta, err := readContainerMetadata(file)
+	if err != nil {
 		return nil, nil, nil, err
 	}
 
@@ -1010,12 +1161,7 @@ func loadContainerFile(path string) (*metadataFile, []byte, [][]byte, error) {
 		blobs = append(blobs, data)
 	}
 
-	var meta metadataFile
-	if err := json.Unmarshal(metaBytes, &meta); err != nil {
-		return nil, nil, nil, errors.New("corrupted vault metadata")
-	}
-
-	return &meta, encryptedIndex, blobs, nil
+	return meta, encryptedIndex, blobs, nil
 }
 
 func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
@@ -1050,6 +1196,21 @@ func readFileWithBackup(primary, backup string) ([]byte, error) {
 	return os.ReadFile(backup)
 }
 
+func metadataSectionCipher(meta *metadataFile, metadataKey []byte, legacy *crypto.Cipher) (*crypto.Cipher, error) {
+	version := meta.Version
+	if version == 0 {
+		version = 1
+	}
+	switch version {
+	case 1, 2:
+		return legacy, nil
+	case metadataVersion:
+		return crypto.NewMetadataSaveCipher(metadataKey, meta.SaveSalt)
+	default:
+		return nil, errors.New("unsupported vault metadata version")
+	}
+}
+
 func decryptStoredMnemonic(meta *metadataFile, cipher *crypto.Cipher) ([]string, error) {
 	if meta == nil || cipher == nil || len(meta.EncryptedMnemonic) == 0 {
 		return nil, nil
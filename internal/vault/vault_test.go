@@ -2,12 +2,75 @@ package vault
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"micrypt/internal/bip39"
+	"micrypt/internal/crypto"
 )
 
+func createTestVault(t *testing.T, password string) (*Vault, *bip39.Mnemonic) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vault.mvault")
+	v, mnemonic, err := CreateVault(path, []byte(password), crypto.SingleCipher)
+	if err != nil {
+		t.Fatalf("create vault: %v", err)
+	}
+	return v, mnemonic
+}
+
+func legacyTestCipher(t *testing.T, v *Vault) *crypto.CascadeCipher {
+	t.Helper()
+	legacy, err := v.entryCipher(&FileEntry{})
+	if err != nil {
+		t.Fatalf("legacy cipher: %v", err)
+	}
+	return legacy
+}
+
+func testMasterKey(t *testing.T, v *Vault) []byte {
+	t.Helper()
+	var masterKey []byte
+	if err := v.withKeys(func(keys *crypto.KeySchedule) error {
+		masterKey = append([]byte(nil), keys.MasterKey...)
+		return nil
+	}); err != nil {
+		t.Fatalf("master key: %v", err)
+	}
+	return masterKey
+}
+
+func addTestFile(t *testing.T, v *Vault, name string, content []byte) *FileEntry {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(source, content, 0o600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	entry, err := v.EncryptFile(source)
+	if err != nil {
+		t.Fatalf("encrypt file: %v", err)
+	}
+	return entry
+}
+
+func readTestFile(t *testing.T, v *Vault, encryptedName string) []byte {
+	t.Helper()
+	dest := filepath.Join(t.TempDir(), "out")
+	if err := v.DecryptFile(encryptedName, dest); err != nil {
+		t.Fatalf("decrypt file: %v", err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read decrypted: %v", err)
+	}
+	return data
+}
+
 func TestLoadContainerFileRejectsLargeMetadata(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "vault.mvault")
@@ -51,3 +114,1076 @@ func TestLoadContainerFileRejectsLargeIndex(t *testing.T) {
 		t.Fatal("expected error for large index")
 	}
 }
+
+func TestChangeCredentialsKeepsFileData(t *testing.T) {
+	v, mnemonic := createTestVault(t, "old-password")
+	content := []byte("secret contents")
+	entry := addTestFile(t, v, "notes.txt", content)
+
+	if err := v.ChangeCredentials([]byte("wrong-password"), nil, []byte("new-password"), &UnlockOptions{PIM: 1}); err == nil {
+		t.Fatal("expected wrong current password to be rejected")
+	}
+	if err := v.ChangeCredentials([]byte("old-password"), nil, []byte("new-password"), &UnlockOptions{PIM: 1}); err != nil {
+		t.Fatalf("change credentials: %v", err)
+	}
+	path := v.GetPath()
+	v.Lock()
+
+	if _, err := OpenVault(path, []byte("old-password")); err == nil {
+		t.Fatal("expected old password to be rejected")
+	}
+	reopened, err := OpenVaultWithOptions(path, []byte("new-password"), &UnlockOptions{PIM: 1})
+	if err != nil {
+		t.Fatalf("open with new password: %v", err)
+	}
+	if got := readTestFile(t, reopened, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("file contents changed after credential change")
+	}
+	reopened.Lock()
+
+	recovered, err := OpenVaultFromMnemonicSeed(path, mnemonic.Seed)
+	if err != nil {
+		t.Fatalf("open with mnemonic: %v", err)
+	}
+	recovered.Lock()
+}
+
+func TestRecoverVaultWithSeedReplacesPassword(t *testing.T) {
+	v, mnemonic := createTestVault(t, "forgotten-password")
+	content := []byte("recoverable contents")
+	entry := addTestFile(t, v, "notes.txt", content)
+	path := v.GetPath()
+	v.Lock()
+
+	if _, err := RecoverVaultWithSeed(path, mnemonic.Seed, []byte("short"), nil); err == nil {
+		t.Fatal("expected weak new password to be rejected")
+	}
+	recovered, err := RecoverVaultWithSeed(path, mnemonic.Seed, []byte("replacement-password"), nil)
+	if err != nil {
+		t.Fatalf("recover vault: %v", err)
+	}
+	recovered.Lock()
+
+	if _, err := OpenVault(path, []byte("forgotten-password")); err == nil {
+		t.Fatal("expected old password to be rejected after recovery")
+	}
+	reopened, err := OpenVault(path, []byte("replacement-password"))
+	if err != nil {
+		t.Fatalf("open with new password: %v", err)
+	}
+	defer reopened.Lock()
+	if got := readTestFile(t, reopened, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("file contents changed after recovery")
+	}
+}
+
+func TestReencryptAllResumesAfterInterruption(t *testing.T) {
+	v, _ := createTestVault(t, "reencrypt-password")
+	first := addTestFile(t, v, "first.txt", []byte("first contents"))
+	second := addTestFile(t, v, "second.txt", bytes.Repeat([]byte("second"), 40000))
+	path := v.GetPath()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := v.ReencryptAll(ctx, crypto.AESSerpent, func(done, total int) {
+		if done == 1 {
+			cancel()
+		}
+	})
+	if err == nil {
+		t.Fatal("expected interrupted re-encryption to fail")
+	}
+	v.Lock()
+
+	resumed, err := OpenVault(path, []byte("reencrypt-password"))
+	if err != nil {
+		t.Fatalf("reopen vault: %v", err)
+	}
+	if mode, pending := resumed.PendingReencryption(); !pending || mode != crypto.AESSerpent {
+		t.Fatal("expected pending re-encryption to be reported")
+	}
+	var reported []int
+	if err := resumed.ReencryptAll(context.Background(), crypto.AESSerpent, func(done, total int) {
+		reported = append(reported, done)
+	}); err != nil {
+		t.Fatalf("resume re-encryption: %v", err)
+	}
+	if len(reported) != 2 || reported[1] != 2 {
+		t.Fatalf("unexpected progress reports %v", reported)
+	}
+	resumed.Lock()
+
+	if _, err := os.Stat(path + reencryptDirSuffix); !os.IsNotExist(err) {
+		t.Fatal("expected re-encryption journal to be removed")
+	}
+	reopened, err := OpenVault(path, []byte("reencrypt-password"))
+	if err != nil {
+		t.Fatalf("open converted vault: %v", err)
+	}
+	defer reopened.Lock()
+	if reopened.header.CascadeMode != crypto.AESSerpent {
+		t.Fatal("cascade mode was not updated")
+	}
+	if got := readTestFile(t, reopened, first.EncryptedName); !bytes.Equal(got, []byte("first contents")) {
+		t.Fatal("first file changed after re-encryption")
+	}
+	if got := readTestFile(t, reopened, second.EncryptedName); !bytes.Equal(got, bytes.Repeat([]byte("second"), 40000)) {
+		t.Fatal("second file changed after re-encryption")
+	}
+}
+
+func TestKeySlotsUnlockIndependently(t *testing.T) {
+	v, _ := createTestVault(t, "owner-password")
+	content := []byte("shared contents")
+	entry := addTestFile(t, v, "shared.txt", content)
+	path := v.GetPath()
+
+	if _, err := v.AddKeySlot([]byte("wrong-password"), nil, "alice", []byte("alice-password"), nil); err == nil {
+		t.Fatal("expected invalid credentials to be rejected")
+	}
+	slot, err := v.AddKeySlot([]byte("owner-password"), nil, "alice", []byte("alice-password"), &UnlockOptions{PIM: 2})
+	if err != nil {
+		t.Fatalf("add key slot: %v", err)
+	}
+	slots := v.ListKeySlots()
+	if len(slots) != 2 || !slots[0].Primary || slots[1].Label != "alice" {
+		t.Fatalf("unexpected key slots %+v", slots)
+	}
+	v.Lock()
+
+	shared, err := OpenVaultWithOptions(path, []byte("alice-password"), &UnlockOptions{PIM: 2})
+	if err != nil {
+		t.Fatalf("open with key slot: %v", err)
+	}
+	if got := readTestFile(t, shared, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("file contents differ when opened through key slot")
+	}
+	if err := shared.ChangeCredentials([]byte("alice-password"), &UnlockOptions{PIM: 2}, []byte("alice-rotated"), nil); err != nil {
+		t.Fatalf("change key slot credentials: %v", err)
+	}
+	shared.Lock()
+
+	owner, err := OpenVault(path, []byte("owner-password"))
+	if err != nil {
+		t.Fatalf("open with primary password: %v", err)
+	}
+	if err := owner.RemoveKeySlot([]byte("owner-password"), nil, slot.ID); err != nil {
+		t.Fatalf("remove key slot: %v", err)
+	}
+	owner.Lock()
+
+	if _, err := OpenVault(path, []byte("alice-rotated")); err == nil {
+		t.Fatal("expected removed key slot to be rejected")
+	}
+}
+
+func TestOpenVaultUpgradesWeakKDF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.mvault")
+	weak := &crypto.KDFParams{KDF: crypto.KDFScrypt, LogN: 10, R: 8, P: 1, KeyLength: 64}
+	v, _, err := CreateVaultWithEntropyOptions(path, []byte("kdf-password"), crypto.SingleCipher, nil, &VaultCreationOptions{KDFParams: weak})
+	if err != nil {
+		t.Fatalf("create vault: %v", err)
+	}
+	if v.kdfMeta.Params.KDF != crypto.KDFScrypt || v.kdfMeta.Params.LogN != 10 {
+		t.Fatalf("unexpected creation params: %+v", v.kdfMeta.Params)
+	}
+	v.Lock()
+
+	reopened, err := OpenVault(path, []byte("kdf-password"))
+	if err != nil {
+		t.Fatalf("open vault: %v", err)
+	}
+	if reopened.kdfMeta.Params.LogN != crypto.ScryptDefaultLogN {
+		t.Fatalf("expected scrypt cost to be upgraded, got log_n=%d", reopened.kdfMeta.Params.LogN)
+	}
+	if err := reopened.UnlockReport().KDFUpgradeError; err != nil {
+		t.Fatalf("unexpected upgrade error: %v", err)
+	}
+	reopened.Lock()
+
+	again, err := OpenVault(path, []byte("kdf-password"))
+	if err != nil {
+		t.Fatalf("open after upgrade: %v", err)
+	}
+	again.Lock()
+}
+
+func TestLegacyStreamBlobsStillDecrypt(t *testing.T) {
+	v, _ := createTestVault(t, "legacy-password")
+	content := []byte("written before stream framing")
+	entry := addTestFile(t, v, "legacy.txt", content)
+
+	var legacy bytes.Buffer
+	if err := legacyTestCipher(t, v).EncryptStreamVersion(bytes.NewReader(content), &legacy, crypto.StreamVersion1, nil); err != nil {
+		t.Fatalf("encrypt legacy blob: %v", err)
+	}
+	stored := &v.index.Files[0]
+	stored.StreamVersion = 0
+	stored.WrappedKey = nil
+	stored.CipherMAC = v.blobMAC(stored, legacy.Bytes())
+	v.fileData[entry.EncryptedName] = legacy.Bytes()
+	if err := v.saveMetadata(); err != nil {
+		t.Fatalf("save metadata: %v", err)
+	}
+	path := v.GetPath()
+	v.Lock()
+
+	reopened, err := OpenVault(path, []byte("legacy-password"))
+	if err != nil {
+		t.Fatalf("open vault: %v", err)
+	}
+	defer reopened.Lock()
+	if got := readTestFile(t, reopened, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("legacy blob decrypted to different contents")
+	}
+}
+
+func TestSwappedBlobsAreRejected(t *testing.T) {
+	v, _ := createTestVault(t, "binding-password")
+	defer v.Lock()
+	first := addTestFile(t, v, "first.txt", []byte("first contents"))
+	second := addTestFile(t, v, "second.txt", []byte("second contents"))
+
+	firstData, secondData := v.fileData[first.EncryptedName], v.fileData[second.EncryptedName]
+	v.fileData[first.EncryptedName], v.fileData[second.EncryptedName] = secondData, firstData
+	v.index.Files[0].CipherMAC, v.index.Files[1].CipherMAC = v.index.Files[1].CipherMAC, v.index.Files[0].CipherMAC
+	if err := v.DecryptFile(first.EncryptedName, filepath.Join(t.TempDir(), "out")); err == nil {
+		t.Fatal("expected blob moved to another entry to be rejected")
+	}
+	v.fileData[first.EncryptedName], v.fileData[second.EncryptedName] = firstData, secondData
+	v.index.Files[0].CipherMAC, v.index.Files[1].CipherMAC = v.index.Files[1].CipherMAC, v.index.Files[0].CipherMAC
+
+	vaultID := v.header.VaultID
+	v.header.VaultID = "other-vault"
+	if err := v.DecryptFile(first.EncryptedName, filepath.Join(t.TempDir(), "out")); err == nil {
+		t.Fatal("expected blob from another vault to be rejected")
+	}
+	v.header.VaultID = vaultID
+	if got := readTestFile(t, v, first.EncryptedName); !bytes.Equal(got, []byte("first contents")) {
+		t.Fatal("unexpected contents after restoring binding")
+	}
+}
+
+func TestSaveMetadataUsesFreshSaveSalt(t *testing.T) {
+	v, _ := createTestVault(t, "metadata-password")
+	path := v.GetPath()
+	first, _, _, err := loadContainerFile(path)
+	if err != nil {
+		t.Fatalf("load container: %v", err)
+	}
+	addTestFile(t, v, "later.txt", []byte("later"))
+	v.Lock()
+
+	second, _, _, err := loadContainerFile(path)
+	if err != nil {
+		t.Fatalf("load container: %v", err)
+	}
+	if first.Version != metadataVersion || len(second.SaveSalt) != crypto.SaltLength {
+		t.Fatalf("expected version %d metadata with a save salt", metadataVersion)
+	}
+	if bytes.Equal(first.SaveSalt, second.SaveSalt) {
+		t.Fatal("expected each save to use a fresh salt")
+	}
+	metaOnly, err := loadContainerMetadata(path)
+	if err != nil {
+		t.Fatalf("load metadata: %v", err)
+	}
+	if !bytes.Equal(metaOnly.SaveSalt, second.SaveSalt) || !bytes.Equal(metaOnly.AuthMAC, second.AuthMAC) {
+		t.Fatal("expected the metadata-only loader to read the same metadata section")
+	}
+
+	reopened, err := OpenVault(path, []byte("metadata-password"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Lock()
+	if len(reopened.ListFiles()) != 1 {
+		t.Fatal("expected index to survive the round trip")
+	}
+}
+
+func TestExportedFileKeyDecryptsSingleFile(t *testing.T) {
+	v, _ := createTestVault(t, "file-key-password")
+	defer v.Lock()
+	content := []byte("share just this file")
+	entry := addTestFile(t, v, "shared.txt", content)
+	other := addTestFile(t, v, "private.txt", []byte("keep this one"))
+
+	blob := filepath.Join(t.TempDir(), "shared.bin")
+	if err := v.ExportEncryptedFile(entry.EncryptedName, blob); err != nil {
+		t.Fatalf("export blob: %v", err)
+	}
+	fileKey, err := v.ExportFileKey(entry.EncryptedName)
+	if err != nil {
+		t.Fatalf("export file key: %v", err)
+	}
+	defer fileKey.Wipe()
+
+	f, err := os.Open(blob)
+	if err != nil {
+		t.Fatalf("open blob: %v", err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	if err := DecryptWithFileKey(fileKey, f, &out); err != nil {
+		t.Fatalf("decrypt with file key: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), content) {
+		t.Fatal("file key decrypted to different contents")
+	}
+
+	var wrong bytes.Buffer
+	if err := DecryptWithFileKey(fileKey, bytes.NewReader(v.fileData[other.EncryptedName]), &wrong); err == nil {
+		t.Fatal("expected file key to be useless for other files")
+	}
+}
+
+func TestRotateMasterKeyRewrapsDataKeys(t *testing.T) {
+	v, mnemonic := createTestVault(t, "rotate-password")
+	content := []byte("survives master key rotation")
+	entry := addTestFile(t, v, "rotate.txt", content)
+	legacyContent := []byte("stored before per-file keys")
+	legacy := addTestFile(t, v, "legacy.txt", legacyContent)
+
+	var legacyBlob bytes.Buffer
+	stored := v.getIndexEntry(legacy.EncryptedName)
+	if err := legacyTestCipher(t, v).EncryptStreamVersion(bytes.NewReader(legacyContent), &legacyBlob, stored.streamVersion(), v.blobAssociatedData(stored)); err != nil {
+		t.Fatalf("encrypt legacy blob: %v", err)
+	}
+	stored.WrappedKey = nil
+	stored.CipherMAC = v.blobMAC(stored, legacyBlob.Bytes())
+	v.fileData[legacy.EncryptedName] = legacyBlob.Bytes()
+
+	blobBefore := append([]byte(nil), v.fileData[entry.EncryptedName]...)
+	masterBefore := testMasterKey(t, v)
+	if err := v.RotateMasterKey([]byte("wrong-password"), nil); err == nil {
+		t.Fatal("expected wrong password to be rejected")
+	}
+	if err := v.RotateMasterKey([]byte("rotate-password"), nil); err != nil {
+		t.Fatalf("rotate master key: %v", err)
+	}
+	if bytes.Equal(testMasterKey(t, v), masterBefore) {
+		t.Fatal("master key did not change")
+	}
+	if !bytes.Equal(v.fileData[entry.EncryptedName], blobBefore) {
+		t.Fatal("rotation rewrote file ciphertext")
+	}
+	rotated := v.getIndexEntry(legacy.EncryptedName)
+	if len(rotated.WrappedKey) == 0 || bytes.Equal(v.fileData[legacy.EncryptedName], legacyBlob.Bytes()) {
+		t.Fatal("expected legacy entry to be re-encrypted under a fresh data key")
+	}
+	fileKey, err := v.ExportFileKey(legacy.EncryptedName)
+	if err != nil {
+		t.Fatalf("export re-encrypted legacy key: %v", err)
+	}
+	if bytes.Equal(fileKey.Key, masterBefore) {
+		t.Fatal("old master key was kept as a data key")
+	}
+	fileKey.Wipe()
+	path := v.GetPath()
+	v.Lock()
+
+	reopened, err := OpenVault(path, []byte("rotate-password"))
+	if err != nil {
+		t.Fatalf("open after rotation: %v", err)
+	}
+	if got := readTestFile(t, reopened, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("file contents changed after rotation")
+	}
+	if got := readTestFile(t, reopened, legacy.EncryptedName); !bytes.Equal(got, legacyContent) {
+		t.Fatal("legacy contents changed after rotation")
+	}
+	reopened.Lock()
+
+	recovered, err := OpenVaultFromMnemonicSeed(path, mnemonic.Seed)
+	if err != nil {
+		t.Fatalf("open with mnemonic after rotation: %v", err)
+	}
+	recovered.Lock()
+}
+
+func TestRecipientBundleMovesFileBetweenVaults(t *testing.T) {
+	sender, _ := createTestVault(t, "sender-password")
+	defer sender.Lock()
+	receiver, _ := createTestVault(t, "receiver-password")
+	defer receiver.Lock()
+
+	info, err := receiver.GenerateIdentity("Receiver")
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	content := []byte("for the receiver only")
+	entry := addTestFile(t, sender, "shared.txt", content)
+
+	bundle := filepath.Join(t.TempDir(), "shared.mcrb")
+	if err := sender.ExportEntryToRecipients(entry.EncryptedName, []string{info.Recipient}, bundle); err != nil {
+		t.Fatalf("export to recipient: %v", err)
+	}
+	if _, err := sender.ImportRecipientBundle(bundle); err == nil {
+		t.Fatal("expected sender without identity to be rejected")
+	}
+
+	path := receiver.GetPath()
+	receiver.Lock()
+	reopened, err := OpenVault(path, []byte("receiver-password"))
+	if err != nil {
+		t.Fatalf("reopen receiver: %v", err)
+	}
+	defer reopened.Lock()
+	if got := reopened.ListIdentities(); len(got) != 1 || got[0].Recipient != info.Recipient {
+		t.Fatal("identity was not persisted")
+	}
+
+	imported, err := reopened.ImportRecipientBundle(bundle)
+	if err != nil {
+		t.Fatalf("import bundle: %v", err)
+	}
+	if imported.OriginalName != "shared.txt" {
+		t.Fatalf("unexpected name %q", imported.OriginalName)
+	}
+	if got := readTestFile(t, reopened, imported.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("bundle contents differ")
+	}
+
+	secret, err := reopened.ExportIdentity(info.ID)
+	if err != nil {
+		t.Fatalf("export identity: %v", err)
+	}
+	if _, err := sender.ImportIdentity("Copy", secret); err != nil {
+		t.Fatalf("import identity: %v", err)
+	}
+	if _, err := sender.ImportRecipientBundle(bundle); err != nil {
+		t.Fatalf("import bundle with copied identity: %v", err)
+	}
+}
+
+func TestDropboxFilesAreIngestedOnUnlock(t *testing.T) {
+	v, _ := createTestVault(t, "dropbox-password")
+	path := v.GetPath()
+
+	source := filepath.Join(t.TempDir(), "report.txt")
+	content := []byte("deposited while locked")
+	if err := os.WriteFile(source, content, 0o600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	if err := AddToDropbox(path, source); err == nil {
+		t.Fatal("expected drop box to be disabled by default")
+	}
+	if _, err := v.EnableDropbox(); err != nil {
+		t.Fatalf("enable drop box: %v", err)
+	}
+	v.Lock()
+
+	if err := AddToDropbox(path, source); err != nil {
+		t.Fatalf("add to drop box: %v", err)
+	}
+	if pending, _ := pendingDropboxFiles(path); len(pending) != 1 {
+		t.Fatalf("expected one pending file, got %d", len(pending))
+	}
+
+	reopened, err := OpenVault(path, []byte("dropbox-password"))
+	if err != nil {
+		t.Fatalf("open vault: %v", err)
+	}
+	defer reopened.Lock()
+	files := reopened.ListFiles()
+	if len(files) != 1 || files[0].OriginalName != "report.txt" {
+		t.Fatalf("expected deposited file in index, got %+v", files)
+	}
+	if got := readTestFile(t, reopened, files[0].EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("deposited contents differ")
+	}
+	if pending, _ := pendingDropboxFiles(path); len(pending) != 0 {
+		t.Fatal("expected drop box to be emptied after ingest")
+	}
+}
+
+func TestDropboxIngestOnRecoveryReportsFailures(t *testing.T) {
+	v, mnemonic := createTestVault(t, "dropbox-password")
+	path := v.GetPath()
+	if _, err := v.EnableDropbox(); err != nil {
+		t.Fatalf("enable drop box: %v", err)
+	}
+	v.Lock()
+
+	source := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(source, []byte("deposited while locked"), 0o600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	if err := AddToDropbox(path, source); err != nil {
+		t.Fatalf("add to drop box: %v", err)
+	}
+	corrupt := filepath.Join(path+dropboxDirSuffix, "corrupt"+dropboxFileSuffix)
+	if err := os.WriteFile(corrupt, []byte("not a bundle"), 0o600); err != nil {
+		t.Fatalf("write corrupt deposit: %v", err)
+	}
+
+	recovered, err := OpenVaultFromMnemonicSeed(path, mnemonic.Seed)
+	if err != nil {
+		t.Fatalf("open with mnemonic: %v", err)
+	}
+	defer recovered.Lock()
+	report := recovered.UnlockReport()
+	if report.DropboxIngested != 1 || report.DropboxFailed != 1 || report.DropboxError == nil {
+		t.Fatalf("expected one ingested and one failed deposit, got %+v", report)
+	}
+	if len(recovered.ListFiles()) != 1 {
+		t.Fatalf("expected the valid deposit in the index, got %d files", len(recovered.ListFiles()))
+	}
+	if pending, _ := pendingDropboxFiles(path); len(pending) != 1 || pending[0] != corrupt {
+		t.Fatalf("expected the corrupt deposit to stay pending, got %v", pending)
+	}
+}
+
+func TestDropboxIngestIsNotRepeatedAfterInterruptedCleanup(t *testing.T) {
+	v, _ := createTestVault(t, "dropbox-password")
+	path := v.GetPath()
+	if _, err := v.EnableDropbox(); err != nil {
+		t.Fatalf("enable drop box: %v", err)
+	}
+	v.Lock()
+
+	for _, name := range []string{"first.txt", "second.txt"} {
+		source := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(source, []byte(name), 0o600); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+		if err := AddToDropbox(path, source); err != nil {
+			t.Fatalf("add to drop box: %v", err)
+		}
+	}
+	pending, _ := pendingDropboxFiles(path)
+	saved := make(map[string][]byte, len(pending))
+	for _, file := range pending {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read deposit: %v", err)
+		}
+		saved[file] = data
+	}
+
+	reopened, err := OpenVault(path, []byte("dropbox-password"))
+	if err != nil {
+		t.Fatalf("open vault: %v", err)
+	}
+	if report := reopened.UnlockReport(); report.DropboxIngested != 2 || report.DropboxFailed != 0 {
+		t.Fatalf("expected both deposits in one batch, got %+v", report)
+	}
+	reopened.Lock()
+
+	for file, data := range saved {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatalf("restore deposit: %v", err)
+		}
+	}
+	again, err := OpenVault(path, []byte("dropbox-password"))
+	if err != nil {
+		t.Fatalf("reopen vault: %v", err)
+	}
+	defer again.Lock()
+	if report := again.UnlockReport(); report.DropboxIngested != 0 || report.DropboxError != nil {
+		t.Fatalf("expected imported deposits to be skipped, got %+v", report)
+	}
+	if len(again.ListFiles()) != 2 {
+		t.Fatalf("expected two files after the second unlock, got %d", len(again.ListFiles()))
+	}
+	if pending, _ := pendingDropboxFiles(path); len(pending) != 0 {
+		t.Fatalf("expected leftover deposits to be removed, got %v", pending)
+	}
+}
+
+func TestDropboxRecipientIsAuthenticated(t *testing.T) {
+	v, _ := createTestVault(t, "dropbox-password")
+	path := v.GetPath()
+	if _, err := v.EnableDropbox(); err != nil {
+		t.Fatalf("enable drop box: %v", err)
+	}
+	v.Lock()
+
+	attacker, err := crypto.GenerateIdentity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read container: %v", err)
+	}
+	offset := len(containerMagic) + 4
+	metaLen := int(binary.BigEndian.Uint32(raw[offset:]))
+	var meta metadataFile
+	if err := json.Unmarshal(raw[offset+4:offset+4+metaLen], &meta); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	meta.DropboxRecipient = attacker.Recipient().String()
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatalf("encode metadata: %v", err)
+	}
+	var tampered bytes.Buffer
+	tampered.Write(raw[:offset])
+	if err := binary.Write(&tampered, binary.BigEndian, uint32(len(metaBytes))); err != nil {
+		t.Fatalf("write meta len: %v", err)
+	}
+	tampered.Write(metaBytes)
+	tampered.Write(raw[offset+4+metaLen:])
+	if err := os.WriteFile(path, tampered.Bytes(), 0o600); err != nil {
+		t.Fatalf("write container: %v", err)
+	}
+
+	if _, err := OpenVault(path, []byte("dropbox-password")); err == nil {
+		t.Fatal("expected a swapped drop-box recipient to fail authentication")
+	}
+}
+
+func TestAgeExportAndImport(t *testing.T) {
+	v, _ := createTestVault(t, "age-password")
+	defer v.Lock()
+	content := bytes.Repeat([]byte("age interop "), 10000)
+	entry := addTestFile(t, v, "notes.txt", content)
+
+	identity, err := crypto.GenerateAgeIdentity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	dir := t.TempDir()
+	keyed := filepath.Join(dir, "notes.txt.age")
+	if err := v.ExportEntryAsAge(entry.EncryptedName, keyed, []string{identity.Recipient().String()}, nil); err != nil {
+		t.Fatalf("export to recipient: %v", err)
+	}
+	f, err := os.Open(keyed)
+	if err != nil {
+		t.Fatalf("open export: %v", err)
+	}
+	var out bytes.Buffer
+	err = crypto.DecryptAge(&out, f, identity)
+	f.Close()
+	if err != nil || !bytes.Equal(out.Bytes(), content) {
+		t.Fatalf("exported age file does not decrypt: %v", err)
+	}
+
+	imported, err := v.ImportAge(keyed, identity.String(), nil)
+	if err != nil {
+		t.Fatalf("import with identity: %v", err)
+	}
+	if imported.OriginalName != "notes.txt" || imported.Size != int64(len(content)) {
+		t.Fatalf("unexpected imported entry %q of %d bytes", imported.OriginalName, imported.Size)
+	}
+	if got := readTestFile(t, v, imported.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("imported contents differ")
+	}
+
+	protected := filepath.Join(dir, "protected.age")
+	if err := v.ExportEntryAsAge(entry.EncryptedName, protected, nil, []byte("shared passphrase")); err != nil {
+		t.Fatalf("export with passphrase: %v", err)
+	}
+	if _, err := v.ImportAge(protected, "", []byte("wrong passphrase")); err == nil {
+		t.Fatal("expected wrong passphrase to be rejected")
+	}
+	if _, err := v.ImportAge(protected, "", []byte("shared passphrase")); err != nil {
+		t.Fatalf("import with passphrase: %v", err)
+	}
+}
+
+func TestRecoverySharesOpenVault(t *testing.T) {
+	v, _ := createTestVault(t, "shares-password")
+	content := []byte("recoverable by any three")
+	entry := addTestFile(t, v, "team.txt", content)
+
+	if _, err := v.CreateRecoveryShares([]byte("wrong-password"), nil, nil, 3, 5); err == nil {
+		t.Fatal("expected wrong password to be rejected")
+	}
+	shares, err := v.CreateRecoveryShares([]byte("shares-password"), nil, nil, 3, 5)
+	if err != nil {
+		t.Fatalf("create shares: %v", err)
+	}
+	if len(shares[0]) != len(v.StoredMnemonic())+1 {
+		t.Fatalf("expected shares as long as the phrase plus a header word, got %d words", len(shares[0]))
+	}
+	if _, err := v.CreateRecoveryShares([]byte("shares-password"), nil, nil, 1, 3); err == nil {
+		t.Fatal("expected a threshold of one to be rejected")
+	}
+	path := v.GetPath()
+	v.Lock()
+
+	if _, err := OpenVaultFromRecoveryShares(path, shares[:2], nil); err == nil {
+		t.Fatal("expected too few shares to be rejected")
+	}
+	recovered, err := OpenVaultFromRecoveryShares(path, [][]string{shares[4], shares[1], shares[2]}, nil)
+	if err != nil {
+		t.Fatalf("open with shares: %v", err)
+	}
+	defer recovered.Lock()
+	if got := readTestFile(t, recovered, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("recovered contents differ")
+	}
+}
+
+func TestRecoveryPassphraseIsRequiredForSeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.mvault")
+	options := &VaultCreationOptions{MnemonicWords: 24, RecoveryPassphrase: []byte("twenty-fifth word")}
+	v, mnemonic, err := CreateVaultWithEntropyOptions(path, []byte("passphrase-password"), crypto.SingleCipher, nil, options)
+	if err != nil {
+		t.Fatalf("create vault: %v", err)
+	}
+	if len(mnemonic.Words) != 24 {
+		t.Fatalf("expected 24 words, got %d", len(mnemonic.Words))
+	}
+	if !v.RecoveryUsesPassphrase() {
+		t.Fatal("expected vault to record the recovery passphrase")
+	}
+	if _, err := v.CreateRecoveryShares([]byte("passphrase-password"), nil, nil, 2, 3); err == nil || !strings.Contains(err.Error(), "passphrase is incorrect") {
+		t.Fatalf("expected shares without the passphrase to be rejected, got %v", err)
+	}
+	kit := filepath.Join(t.TempDir(), "kit.html")
+	if err := v.WriteRecoveryKit(mnemonic.Words, nil, kit); err == nil {
+		t.Fatal("expected a kit without the passphrase to be refused")
+	}
+	if err := v.WriteRecoveryKit(mnemonic.Words, []byte("wrong word"), kit); err == nil {
+		t.Fatal("expected a kit with the wrong passphrase to be refused")
+	}
+	if err := v.WriteRecoveryKit(mnemonic.Words, []byte("twenty-fifth word"), kit); err != nil {
+		t.Fatalf("write kit with passphrase: %v", err)
+	}
+	shares, err := v.CreateRecoveryShares([]byte("passphrase-password"), nil, []byte("twenty-fifth word"), 2, 3)
+	if err != nil {
+		t.Fatalf("create shares with passphrase: %v", err)
+	}
+	path = v.GetPath()
+	v.Lock()
+
+	if _, err := OpenVaultFromRecoveryShares(path, shares[:2], nil); err == nil {
+		t.Fatal("expected shares without the passphrase to be rejected")
+	}
+	fromShares, err := OpenVaultFromRecoveryShares(path, shares[1:], []byte("twenty-fifth word"))
+	if err != nil {
+		t.Fatalf("open with shares and passphrase: %v", err)
+	}
+	fromShares.Lock()
+
+	withoutPassphrase, err := bip39.RestoreFromMnemonic(mnemonic.Words, nil)
+	if err != nil {
+		t.Fatalf("restore mnemonic: %v", err)
+	}
+	if _, err := OpenVaultFromMnemonicSeed(path, withoutPassphrase.Seed); err == nil {
+		t.Fatal("expected words without passphrase to be rejected")
+	}
+	withPassphrase, err := bip39.RestoreFromMnemonic(mnemonic.Words, []byte("twenty-fifth word"))
+	if err != nil {
+		t.Fatalf("restore mnemonic: %v", err)
+	}
+	recovered, err := OpenVaultFromMnemonicSeed(path, withPassphrase.Seed)
+	if err != nil {
+		t.Fatalf("open with passphrase: %v", err)
+	}
+	recovered.Lock()
+}
+
+func TestRotateRecoveryPhraseRevokesOldPhrase(t *testing.T) {
+	v, oldMnemonic := createTestVault(t, "rotation-password")
+	content := []byte("still readable after rotation")
+	entry := addTestFile(t, v, "kept.txt", content)
+
+	if _, err := v.RotateRecoveryPhrase([]byte("wrong-password"), nil, nil); err == nil {
+		t.Fatal("expected wrong password to be rejected")
+	}
+	wordCount := v.index.RecoveryWords
+	v.index.RecoveryWords = 18
+	if _, err := v.RotateRecoveryPhrase([]byte("rotation-password"), nil, nil); err == nil {
+		t.Fatal("expected an unsupported word count to be rejected")
+	}
+	v.index.RecoveryWords = wordCount
+	newMnemonic, err := v.RotateRecoveryPhrase([]byte("rotation-password"), nil, nil)
+	if err != nil {
+		t.Fatalf("rotate recovery phrase: %v", err)
+	}
+	if strings.Join(newMnemonic.Words, " ") == strings.Join(oldMnemonic.Words, " ") {
+		t.Fatal("expected a new recovery phrase")
+	}
+	path := v.GetPath()
+	v.Lock()
+
+	if _, err := OpenVaultFromMnemonicSeed(path, oldMnemonic.Seed); err == nil {
+		t.Fatal("expected old recovery phrase to be rejected")
+	}
+	recovered, err := OpenVaultFromMnemonicSeed(path, newMnemonic.Seed)
+	if err != nil {
+		t.Fatalf("open with new phrase: %v", err)
+	}
+	if got := readTestFile(t, recovered, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("contents differ after rotation")
+	}
+	recovered.Lock()
+
+	reopened, err := OpenVault(path, []byte("rotation-password"))
+	if err != nil {
+		t.Fatalf("open with password: %v", err)
+	}
+	defer reopened.Lock()
+	if got := strings.Join(reopened.StoredMnemonic(), " "); got != strings.Join(newMnemonic.Words, " ") {
+		t.Fatal("stored mnemonic was not replaced")
+	}
+}
+
+func TestRotateRecoveryPhraseKeepsKeySlotsAndRecoveryFile(t *testing.T) {
+	v, oldMnemonic := createTestVault(t, "rotation-password")
+	content := []byte("shared before rotation")
+	entry := addTestFile(t, v, "shared.txt", content)
+	path := v.GetPath()
+	recoveryPath := filepath.Join(t.TempDir(), "vault.recovery")
+
+	if _, err := v.AddKeySlot([]byte("rotation-password"), nil, "alice", []byte("alice-password"), nil); err != nil {
+		t.Fatalf("add key slot: %v", err)
+	}
+	if err := v.CreateRecoveryFile([]byte("rotation-password"), nil, recoveryPath); err != nil {
+		t.Fatalf("create recovery file: %v", err)
+	}
+	v.Lock()
+
+	shared, err := OpenVault(path, []byte("alice-password"))
+	if err != nil {
+		t.Fatalf("open with key slot: %v", err)
+	}
+	newMnemonic, err := shared.RotateRecoveryPhrase([]byte("alice-password"), nil, nil)
+	if err != nil {
+		t.Fatalf("rotate recovery phrase through key slot: %v", err)
+	}
+	shared.Lock()
+
+	if _, err := OpenVaultFromMnemonicSeed(path, oldMnemonic.Seed); err == nil {
+		t.Fatal("expected old recovery phrase to be rejected")
+	}
+	opened := []func() (*Vault, error){
+		func() (*Vault, error) { return OpenVaultFromMnemonicSeed(path, newMnemonic.Seed) },
+		func() (*Vault, error) { return OpenVault(path, []byte("rotation-password")) },
+		func() (*Vault, error) { return OpenVault(path, []byte("alice-password")) },
+		func() (*Vault, error) { return OpenVaultFromRecoveryFile(path, recoveryPath) },
+	}
+	for i, open := range opened {
+		reopened, err := open()
+		if err != nil {
+			t.Fatalf("credential %d after rotation: %v", i, err)
+		}
+		if got := readTestFile(t, reopened, entry.EncryptedName); !bytes.Equal(got, content) {
+			t.Fatalf("credential %d: contents differ after rotation", i)
+		}
+		reopened.Lock()
+	}
+}
+
+func TestRecoveryPhraseCanBeOmittedOrForgotten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.mvault")
+	options := &VaultCreationOptions{OmitStoredMnemonic: true}
+	omitted, mnemonic, err := CreateVaultWithEntropyOptions(path, []byte("omitted-password"), crypto.SingleCipher, nil, options)
+	if err != nil {
+		t.Fatalf("create vault: %v", err)
+	}
+	if omitted.HasStoredMnemonic() {
+		t.Fatal("expected recovery phrase not to be stored")
+	}
+	path = omitted.GetPath()
+	omitted.Lock()
+	recovered, err := OpenVaultFromMnemonicSeed(path, mnemonic.Seed)
+	if err != nil {
+		t.Fatalf("open with seed: %v", err)
+	}
+	if words := recovered.StoredMnemonic(); len(words) != 0 {
+		t.Fatal("expected no stored recovery phrase after reopening")
+	}
+	recovered.Lock()
+
+	v, _ := createTestVault(t, "forget-password")
+	if !v.HasStoredMnemonic() {
+		t.Fatal("expected recovery phrase to be stored by default")
+	}
+	if err := v.ForgetRecoveryPhrase([]byte("wrong-password"), nil); err == nil {
+		t.Fatal("expected wrong password to be rejected")
+	}
+	if err := v.ForgetRecoveryPhrase([]byte("forget-password"), nil); err != nil {
+		t.Fatalf("forget recovery phrase: %v", err)
+	}
+	path = v.GetPath()
+	v.Lock()
+	reopened, err := OpenVault(path, []byte("forget-password"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Lock()
+	if reopened.HasStoredMnemonic() {
+		t.Fatal("expected forgotten recovery phrase to stay removed")
+	}
+}
+
+func TestRecoveryFileOpensAndResetsVault(t *testing.T) {
+	v, _ := createTestVault(t, "file-password")
+	content := []byte("kept in the safe")
+	entry := addTestFile(t, v, "safe.txt", content)
+
+	recoveryPath := filepath.Join(t.TempDir(), "vault.recovery")
+	if err := v.CreateRecoveryFile([]byte("wrong-password"), nil, recoveryPath); err == nil {
+		t.Fatal("expected wrong password to be rejected")
+	}
+	if err := v.CreateRecoveryFile([]byte("file-password"), nil, recoveryPath); err != nil {
+		t.Fatalf("create recovery file: %v", err)
+	}
+	path := v.GetPath()
+	v.Lock()
+
+	opened, err := OpenVaultFromRecoveryFile(path, recoveryPath)
+	if err != nil {
+		t.Fatalf("open with recovery file: %v", err)
+	}
+	if got := readTestFile(t, opened, entry.EncryptedName); !bytes.Equal(got, content) {
+		t.Fatal("contents differ")
+	}
+	opened.Lock()
+
+	recovered, err := RecoverVaultWithRecoveryFile(path, recoveryPath, []byte("replacement-password"), nil)
+	if err != nil {
+		t.Fatalf("recover with file: %v", err)
+	}
+	if err := recovered.RemoveRecoveryFile([]byte("replacement-password"), nil); err != nil {
+		t.Fatalf("remove recovery file: %v", err)
+	}
+	recovered.Lock()
+
+	if _, err := OpenVault(path, []byte("file-password")); err == nil {
+		t.Fatal("expected old password to be rejected")
+	}
+	if _, err := OpenVaultFromRecoveryFile(path, recoveryPath); err == nil {
+		t.Fatal("expected removed recovery file to be rejected")
+	}
+}
+
+func TestRecoveryKitContainsWordsAndVaultDetails(t *testing.T) {
+	v, mnemonic := createTestVault(t, "kit-password")
+	defer v.Lock()
+
+	other, err := bip39.GenerateMnemonic(bip39.Mnemonic12Words)
+	if err != nil {
+		t.Fatalf("generate mnemonic: %v", err)
+	}
+	dest := filepath.Join(t.TempDir(), "kit.html")
+	if err := v.WriteRecoveryKit(other.Words, nil, dest); err == nil {
+		t.Fatal("expected a phrase from another vault to be rejected")
+	}
+	if err := v.WriteRecoveryKit(mnemonic.Words, nil, dest); err != nil {
+		t.Fatalf("write recovery kit: %v", err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read kit: %v", err)
+	}
+	page := string(data)
+	for _, want := range append([]string{v.header.VaultID, "<svg", "argon2id"}, mnemonic.Words...) {
+		if !strings.Contains(page, want) {
+			t.Fatalf("recovery kit is missing %q", want)
+		}
+	}
+	if err := v.WriteRecoveryKit(mnemonic.Words, nil, dest); err == nil {
+		t.Fatal("expected existing destination to be refused")
+	}
+}
+
+func TestVaultKeysStaySealedAndAreWipedOnLock(t *testing.T) {
+	v, _ := createTestVault(t, "enclave-password")
+	content := []byte("kept behind guard pages")
+	entry := addTestFile(t, v, "enclave.txt", content)
+
+	keys := v.keys
+	if keys == nil {
+		t.Fatal("expected an unlocked vault to hold its key schedule")
+	}
+	if v.KeysGuarded() && !keys.Sealed() {
+		t.Fatal("expected key schedule to be sealed while idle")
+	}
+	if err := v.DecryptFile(entry.EncryptedName, filepath.Join(t.TempDir(), "out.txt")); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if v.KeysGuarded() && !keys.Sealed() {
+		t.Fatal("expected key schedule to be sealed again after use")
+	}
+
+	v.Lock()
+	if v.keys != nil || v.KeysLocked() {
+		t.Fatal("expected lock to release the key schedule")
+	}
+	if err := keys.Open(); err == nil {
+		t.Fatal("expected wiped key schedule to refuse access")
+	}
+}
+
+func TestIdentitySecretsStaySealedAndAreWipedOnLock(t *testing.T) {
+	v, _ := createTestVault(t, "identity-enclave-password")
+	path := v.GetPath()
+	info, err := v.GenerateIdentity("Sealed")
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	if _, err := v.EnableDropbox(); err != nil {
+		t.Fatalf("enable drop box: %v", err)
+	}
+	exported, err := v.ExportIdentity(info.ID)
+	if err != nil {
+		t.Fatalf("export identity: %v", err)
+	}
+	v.Lock()
+
+	source := filepath.Join(t.TempDir(), "sealed.txt")
+	if err := os.WriteFile(source, []byte("deposited for a sealed identity"), 0o600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	if err := AddToDropbox(path, source); err != nil {
+		t.Fatalf("add to drop box: %v", err)
+	}
+
+	reopened, err := OpenVault(path, []byte("identity-enclave-password"))
+	if err != nil {
+		t.Fatalf("open vault: %v", err)
+	}
+	if len(reopened.ListFiles()) != 1 {
+		t.Fatal("expected the deposit to be ingested with the sealed drop-box secret")
+	}
+	var enclaves []*crypto.Enclave
+	for _, stored := range []*StoredIdentity{reopened.index.Dropbox, reopened.findIdentity(info.ID)} {
+		if stored == nil || stored.secret == nil || len(stored.Secret) != 0 {
+			t.Fatal("expected identity secrets to be held in an enclave only")
+		}
+		if reopened.KeysGuarded() && !stored.secret.Sealed() {
+			t.Fatal("expected identity secrets to be sealed while idle")
+		}
+		enclaves = append(enclaves, stored.secret)
+	}
+	again, err := reopened.ExportIdentity(info.ID)
+	if err != nil || again != exported {
+		t.Fatalf("expected sealed identity to export unchanged: %v", err)
+	}
+
+	reopened.Lock()
+	for _, enclave := range enclaves {
+		if _, err := enclave.Open(); err == nil {
+			t.Fatal("expected lock to destroy identity enclaves")
+		}
+	}
+}
+
+func TestCredentialBuffersAreWipedAfterUse(t *testing.T) {
+	v, _ := createTestVault(t, "credential-password")
+	path := v.GetPath()
+
+	password := []byte("credential-password")
+	if err := v.VerifyPassword(password, nil); err != nil {
+		t.Fatalf("verify password: %v", err)
+	}
+	if !bytes.Equal(password, make([]byte, len(password))) {
+		t.Fatal("expected verify to wipe the password buffer")
+	}
+	v.Lock()
+
+	password = []byte("credential-password")
+	keyfile := []byte("not a keyfile of this vault")
+	if _, err := OpenVaultWithOptions(path, password, &UnlockOptions{Keyfiles: [][]byte{keyfile}}); err == nil {
+		t.Fatal("expected open with an unknown keyfile to fail")
+	}
+	if !bytes.Equal(password, make([]byte, len(password))) || !bytes.Equal(keyfile, make([]byte, len(keyfile))) {
+		t.Fatal("expected a failed open to wipe the password and keyfile buffers")
+	}
+}